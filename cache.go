@@ -0,0 +1,294 @@
+package yago
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// CacheStore stores the cached query results. The entries are tagged with
+// the tables read by the query, so they can be invalidated when the tables
+// are written.
+// Implementations must be safe for concurrent use.
+type CacheStore interface {
+	Get(key string) (value interface{}, ok bool)
+	Set(key string, value interface{}, tables []string, ttl time.Duration)
+	InvalidateTables(tables ...string)
+}
+
+// lruEntry is a LRUCache entry
+type lruEntry struct {
+	key     string
+	value   interface{}
+	tables  []string
+	expires time.Time
+}
+
+// LRUCache is a in-memory CacheStore that holds a maximum number of
+// entries, and evicts the least recently used ones
+type LRUCache struct {
+	mu      sync.Mutex
+	size    int
+	ll      *list.List
+	entries map[string]*list.Element
+}
+
+// NewLRUCache returns a LRUCache that holds at most size entries
+func NewLRUCache(size int) *LRUCache {
+	return &LRUCache{
+		size:    size,
+		ll:      list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// Len returns the number of entries in the cache
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// Get returns a cached value, if present and not expired
+func (c *LRUCache) Get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*lruEntry)
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		c.remove(elem)
+		return nil, false
+	}
+	c.ll.MoveToFront(elem)
+	return entry.value, true
+}
+
+// Set stores a value. A zero ttl never expires.
+func (c *LRUCache) Set(key string, value interface{}, tables []string, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := &lruEntry{key: key, value: value, tables: tables}
+	if ttl != 0 {
+		entry.expires = time.Now().Add(ttl)
+	}
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.ll.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.ll.PushFront(entry)
+	for c.size > 0 && c.ll.Len() > c.size {
+		c.remove(c.ll.Back())
+	}
+}
+
+// InvalidateTables removes the entries that read any of the tables
+func (c *LRUCache) InvalidateTables(tables ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for elem := c.ll.Front(); elem != nil; {
+		next := elem.Next()
+		if containsAny(elem.Value.(*lruEntry).tables, tables) {
+			c.remove(elem)
+		}
+		elem = next
+	}
+}
+
+func (c *LRUCache) remove(elem *list.Element) {
+	c.ll.Remove(elem)
+	delete(c.entries, elem.Value.(*lruEntry).key)
+}
+
+func containsAny(list []string, values []string) bool {
+	for _, s := range list {
+		for _, v := range values {
+			if s == v {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// queryCache is the query result cache of a DB
+type queryCache struct {
+	store CacheStore
+	ttl   time.Duration
+
+	// generations counts the invalidations of each table, so a result read
+	// before an invalidation is not stored after it
+	mu          sync.Mutex
+	generations map[string]uint64
+}
+
+// EnableCache enables the query result cache. The results of Query.One,
+// All and Count are cached with the given ttl (0 for no expiration), and
+// invalidated when Insert, Update, UpdateExpr or Delete writes a table read
+// by the query. The queries run in a transaction, or with FOR UPDATE, are
+// not cached.
+// The writes that do not go through the DB or a Tx, and the tables read by
+// raw SQL, are not tracked: use InvalidateCache or Query.NoCache.
+func (db *DB) EnableCache(store CacheStore, ttl time.Duration) {
+	db.cache = &queryCache{store: store, ttl: ttl, generations: make(map[string]uint64)}
+}
+
+// DisableCache disables the query result cache
+func (db *DB) DisableCache() {
+	db.cache = nil
+}
+
+// InvalidateCache removes the cached results that read the mappers tables
+func (db *DB) InvalidateCache(mps ...MapperProvider) {
+	var tables []string
+	for _, mp := range mps {
+		tables = append(tables, mp.GetMapper().Table().Name)
+	}
+	db.invalidateTables(tables...)
+}
+
+func (db *DB) invalidateTables(tables ...string) {
+	if db.cache != nil && len(tables) != 0 {
+		db.cache.invalidateTables(tables...)
+	}
+}
+
+// invalidateTables removes the cached results that read the tables
+func (c *queryCache) invalidateTables(tables ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, table := range tables {
+		c.generations[table]++
+	}
+	c.store.InvalidateTables(tables...)
+}
+
+// generationsOf returns the current generations of the tables
+func (c *queryCache) generationsOf(tables []string) []uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	generations := make([]uint64, len(tables))
+	for i, table := range tables {
+		generations[i] = c.generations[table]
+	}
+	return generations
+}
+
+// set stores a result, unless one of the tables it read was invalidated
+// since the generations were taken
+func (c *queryCache) set(key string, value interface{}, tables []string, generations []uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, table := range tables {
+		if c.generations[table] != generations[i] {
+			return
+		}
+	}
+	c.store.Set(key, value, tables, c.ttl)
+}
+
+// afterWrite invalidates the cached results that read the mapper table,
+// unless the write failed
+func (db *DB) afterWrite(err error, mapper Mapper) error {
+	if err == nil {
+		db.invalidateTables(mapper.Table().Name)
+	}
+	return err
+}
+
+// afterWrite records the mapper table, so the cached results that read it
+// are invalidated on Commit
+func (tx Tx) afterWrite(err error, mapper Mapper) error {
+	if err == nil && tx.db.cache != nil {
+		*tx.written = append(*tx.written, mapper.Table().Name)
+	}
+	return err
+}
+
+// NoCache disables the result cache for the query
+func (q Query) NoCache() Query {
+	q.noCache = true
+	return q
+}
+
+// getCache returns the cache to use for the query, if any
+func (q Query) getCache() *queryCache {
+	db, ok := q.db.(*DB)
+	if !ok || db.cache == nil || q.noCache || q.lock != nil || q.err != nil {
+		return nil
+	}
+	return db.cache
+}
+
+// readTables returns the tables read by the query
+func (q Query) readTables() []string {
+	tables := []string{q.mapper.Table().Name}
+	for _, m := range q.joins {
+		tables = append(tables, m.Table().Name)
+	}
+	return append(tables, q.related...)
+}
+
+// key returns the cache key of a query result loaded in dest
+func (c *queryCache) key(q Query, method string, dest interface{}) string {
+	stmt := q.statement().Build(q.db.(*DB).Engine.Dialect())
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%T\x00%s\x00%#v", method, dest, stmt.SQL(), stmt.Bindings())
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// load loads a query result in dest from the cache, or from run which
+// loads it from the database
+func (c *queryCache) load(q Query, method string, dest interface{}, run func() error) error {
+	if reflect.ValueOf(dest).Kind() != reflect.Ptr {
+		return run()
+	}
+	key := c.key(q, method, dest)
+	target := reflect.ValueOf(dest).Elem()
+	if value, ok := c.store.Get(key); ok {
+		target.Set(copyValue(reflect.ValueOf(value)))
+		return nil
+	}
+	tables := q.readTables()
+	generations := c.generationsOf(tables)
+	if err := run(); err != nil {
+		return err
+	}
+	c.set(key, copyValue(target).Interface(), tables, generations)
+	return nil
+}
+
+// copyValue copies a value, so the cached values are not shared with the
+// callers. Slices and pointers to structs are copied, but not the structs
+// fields.
+func copyValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(copyValue(v.Index(i)))
+		}
+		return c
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type().Elem())
+		c.Elem().Set(v.Elem())
+		return c
+	default:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		return c
+	}
+}
@@ -0,0 +1,26 @@
+package yago
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryCacheGenerations(t *testing.T) {
+	c := &queryCache{store: NewLRUCache(10), generations: make(map[string]uint64)}
+	tables := []string{"person", "post"}
+
+	// a result read before an invalidation is not stored
+	generations := c.generationsOf(tables)
+	c.invalidateTables("post")
+	c.set("stale", 1, tables, generations)
+	_, ok := c.store.Get("stale")
+	assert.False(t, ok)
+
+	generations = c.generationsOf(tables)
+	c.invalidateTables("comment")
+	c.set("fresh", 2, tables, generations)
+	value, ok := c.store.Get("fresh")
+	assert.True(t, ok)
+	assert.Equal(t, 2, value)
+}
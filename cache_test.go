@@ -0,0 +1,136 @@
+package yago_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/orus-io/yago"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUCache(t *testing.T) {
+	cache := yago.NewLRUCache(2)
+
+	cache.Set("a", 1, []string{"t1"}, 0)
+	cache.Set("b", 2, []string{"t2"}, 0)
+	_, ok := cache.Get("a")
+	assert.True(t, ok)
+
+	// "b" is the least recently used entry
+	cache.Set("c", 3, []string{"t1", "t2"}, 0)
+	assert.Equal(t, 2, cache.Len())
+	_, ok = cache.Get("b")
+	assert.False(t, ok)
+
+	cache.InvalidateTables("t2")
+	assert.Equal(t, 1, cache.Len())
+	value, ok := cache.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+
+	cache.Set("d", 4, nil, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	_, ok = cache.Get("d")
+	assert.False(t, ok)
+}
+
+func TestQueryCache(t *testing.T) {
+	db, model, cleanup := initModel(t)
+	defer cleanup()
+
+	db.EnableCache(yago.NewLRUCache(100), 0)
+	defer db.DisableCache()
+
+	count := func(q yago.Query) int {
+		var c int
+		assert.Nil(t, q.Count(&c))
+		return c
+	}
+
+	p := SimpleStruct{Name: "one"}
+	assert.Nil(t, db.Insert(&p))
+
+	q := db.Query(model.SimpleStruct)
+	assert.Equal(t, 1, count(q))
+
+	var all []SimpleStruct
+	assert.Nil(t, q.All(&all))
+	assert.Len(t, all, 1)
+	all[0].Name = "changed"
+
+	// Writes that bypass the DB are not seen
+	_, err := db.Engine.DB().Exec("INSERT INTO simple_struct (name, counter) VALUES ('two', 0)")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count(q))
+	assert.Equal(t, 2, count(q.NoCache()))
+
+	// The cached values are not shared with the callers
+	var cached []*SimpleStruct
+	assert.Nil(t, q.All(&cached))
+	assert.Nil(t, q.All(&all))
+	if assert.Len(t, all, 1) {
+		assert.Equal(t, "one", all[0].Name)
+	}
+
+	var one SimpleStruct
+	assert.Nil(t, q.Where(model.SimpleStruct.Name.Eq("one")).One(&one))
+
+	// The DB writes invalidate the cache
+	p.Name = "first"
+	assert.Nil(t, db.Update(&p))
+	assert.Equal(t, 2, count(q))
+	assert.Nil(t, q.Where(model.SimpleStruct.ID.Eq(p.ID)).One(&one))
+	assert.Equal(t, "first", one.Name)
+
+	// The queries on other tables are not invalidated
+	insertRaw := func(name string) {
+		_, err := db.Engine.DB().Exec(
+			"INSERT INTO simple_struct (name, counter) VALUES (?, 0)", name)
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, db.Insert(&SimpleStruct{Name: "three"}))
+	insertRaw("four")
+	assert.Equal(t, 4, count(q))
+	insertRaw("five")
+	assert.Nil(t, db.Insert(&PersonStruct{FirstName: "John"}))
+	assert.Equal(t, 4, count(q))
+
+	// The subqueries tables are tracked
+	sub := q.Where(yago.Exists(db.Query(model.PersonStruct)))
+	assert.Equal(t, 5, count(sub))
+	insertRaw("six")
+	assert.Equal(t, 5, count(sub))
+	assert.Nil(t, db.Insert(&PersonStruct{FirstName: "Jane"}))
+	assert.Equal(t, 6, count(sub))
+	assert.Equal(t, 4, count(q))
+}
+
+func TestQueryCacheTx(t *testing.T) {
+	db, model, cleanup := initModel(t)
+	defer cleanup()
+
+	db.EnableCache(yago.NewLRUCache(100), time.Minute)
+	defer db.DisableCache()
+
+	count := func(q yago.Query) int {
+		var c int
+		assert.Nil(t, q.Count(&c))
+		return c
+	}
+	q := db.Query(model.SimpleStruct)
+	assert.Equal(t, 0, count(q))
+
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	assert.Nil(t, tx.Insert(&SimpleStruct{Name: "one"}))
+	assert.Equal(t, 1, count(tx.Query(model.SimpleStruct)))
+	tx.Rollback()
+	assert.Equal(t, 0, count(q))
+
+	tx, err = db.Begin()
+	assert.Nil(t, err)
+	assert.Nil(t, tx.Insert(&SimpleStruct{Name: "one"}))
+	assert.Equal(t, 0, count(q))
+	assert.Nil(t, tx.Commit())
+	assert.Equal(t, 1, count(q))
+}
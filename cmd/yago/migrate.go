@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/slicebit/qb"
+	_ "github.com/slicebit/qb/dialects/postgres"
+	_ "github.com/slicebit/qb/dialects/sqlite"
+	"github.com/spf13/cobra"
+
+	"github.com/orus-io/yago/migrate"
+)
+
+// MigrateCmd groups the migration subcommands
+var MigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Manage the schema migrations",
+	Long: `Manage the SQL schema migrations of a directory.
+
+The migrations are named "<version>_<name>.up.sql" and
+"<version>_<name>.down.sql". The database is set with --driver and --dsn,
+or the YAGO_DRIVER and YAGO_DSN environment variables.`,
+}
+
+func newMigrator(cmd *cobra.Command) (*qb.Engine, *migrate.Migrator) {
+	flags := cmd.Flags()
+	driver, _ := flags.GetString("driver")
+	dsn, _ := flags.GetString("dsn")
+	dir, _ := flags.GetString("dir")
+	table, _ := flags.GetString("table")
+
+	engine, err := qb.New(driver, dsn)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := migrate.New(engine)
+	m.TableName = table
+	if err := m.LoadDir(dir); err != nil {
+		logger.Fatal(err)
+	}
+	return engine, m
+}
+
+func printMigrations(verb string, done []migrate.Migration) {
+	if len(done) == 0 {
+		fmt.Println("Nothing to do")
+	}
+	for _, mig := range done {
+		fmt.Printf("%s %s\n", verb, mig)
+	}
+}
+
+func parseVersion(cmd *cobra.Command) int64 {
+	to, _ := cmd.Flags().GetString("to")
+	if to == "" {
+		return -1
+	}
+	version, err := strconv.ParseInt(to, 10, 64)
+	if err != nil {
+		logger.Fatalf("Invalid version '%s'", to)
+	}
+	return version
+}
+
+var migrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Apply the pending migrations",
+	Run: func(cmd *cobra.Command, args []string) {
+		engine, m := newMigrator(cmd)
+		defer engine.Close()
+
+		version := parseVersion(cmd)
+		if version < 0 {
+			version = 0
+		}
+		done, err := m.UpTo(version)
+		printMigrations("Applied", done)
+		if err != nil {
+			logger.Fatal(err)
+		}
+	},
+}
+
+var migrateDownCmd = &cobra.Command{
+	Use:   "down",
+	Short: "Revert the last migration, or all the migrations above --to",
+	Run: func(cmd *cobra.Command, args []string) {
+		engine, m := newMigrator(cmd)
+		defer engine.Close()
+
+		var (
+			done []migrate.Migration
+			err  error
+		)
+		if version := parseVersion(cmd); version < 0 {
+			done, err = m.Down()
+		} else {
+			done, err = m.DownTo(version)
+		}
+		printMigrations("Reverted", done)
+		if err != nil {
+			logger.Fatal(err)
+		}
+	},
+}
+
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the state of the migrations",
+	Run: func(cmd *cobra.Command, args []string) {
+		engine, m := newMigrator(cmd)
+		defer engine.Close()
+
+		status, err := m.Status()
+		if err != nil {
+			logger.Fatal(err)
+		}
+		for _, s := range status {
+			state := "pending"
+			if s.Missing {
+				state = "applied " + s.AppliedAt.Format(time.RFC3339) + " (missing)"
+			} else if s.Applied {
+				state = "applied " + s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%d_%s\t%s\n", s.Version, s.Name, state)
+		}
+	},
+}
+
+var migrateCreateCmd = &cobra.Command{
+	Use:   "create NAME",
+	Short: "Create empty up and down scripts, versioned with the current time",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, _ := cmd.Flags().GetString("dir")
+		version, _ := strconv.ParseInt(time.Now().UTC().Format("20060102150405"), 10, 64)
+		up, down, err := migrate.Create(dir, version, strings.Join(args, "_"))
+		if err != nil {
+			logger.Fatal(err)
+		}
+		fmt.Println("Created", up)
+		fmt.Println("Created", down)
+	},
+}
+
+func init() {
+	flags := MigrateCmd.PersistentFlags()
+	flags.String("driver", os.Getenv("YAGO_DRIVER"), "Set the database driver (postgres or sqlite3)")
+	flags.String("dsn", os.Getenv("YAGO_DSN"), "Set the database DSN")
+	flags.String("dir", "migrations", "Set the migrations directory")
+	flags.String("table", migrate.DefaultTableName, "Set the migrations table name")
+
+	migrateUpCmd.Flags().String("to", "", "Apply the migrations up to this version")
+	migrateDownCmd.Flags().String("to", "", "Revert the migrations above this version")
+
+	MigrateCmd.AddCommand(migrateUpCmd, migrateDownCmd, migrateStatusCmd, migrateCreateCmd)
+	RootCmd.AddCommand(MigrateCmd)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/orus-io/yago/generate"
+	"github.com/orus-io/yago/schema"
+)
+
+// ReverseCmd writes the yago structs of an existing database
+var ReverseCmd = &cobra.Command{
+	Use:   "reverse",
+	Short: "Generate the yago structs of an existing database",
+	Long: `Introspect a database and write the yago structs that map its tables,
+and a Model that gives access to them.
+
+The output can be fed to the yago generator. The tables without primary
+key are not mapped.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		flags := cmd.Flags()
+		driver, _ := flags.GetString("driver")
+		dsn, _ := flags.GetString("dsn")
+		schemaName, _ := flags.GetString("schema")
+		pkg, _ := flags.GetString("package")
+		output, _ := flags.GetString("output")
+		tables, _ := flags.GetStringSlice("tables")
+		force, _ := flags.GetBool("force")
+
+		db, err := sql.Open(driver, dsn)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		defer db.Close()
+
+		s, err := schema.Inspect(db, driver, schemaName)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		if len(tables) != 0 {
+			var selected []*schema.Table
+			for _, name := range tables {
+				t := s.Table(name)
+				if t == nil {
+					logger.Fatalf("Unknown table '%s'", name)
+				}
+				selected = append(selected, t)
+			}
+			s.Tables = selected
+		}
+
+		source, err := generate.Reverse(s, driver, pkg).Source()
+		if err != nil {
+			logger.Fatal(err)
+		}
+		if output == "-" {
+			os.Stdout.Write(source)
+			return
+		}
+		if _, err := os.Stat(output); err == nil && !force {
+			logger.Fatalf("%s already exists, use --force to overwrite it", output)
+		}
+		if err := ioutil.WriteFile(output, source, 0644); err != nil {
+			logger.Fatal(err)
+		}
+		fmt.Println("Created", output)
+	},
+}
+
+func init() {
+	flags := ReverseCmd.Flags()
+	flags.String("driver", os.Getenv("YAGO_DRIVER"), "Set the database driver (postgres or sqlite3)")
+	flags.String("dsn", os.Getenv("YAGO_DSN"), "Set the database DSN")
+	flags.String("schema", "", "Set the database schema (postgres only)")
+	flags.StringP("package", "p", "main", "Set the package of the generated file")
+	flags.StringP("output", "o", "model.go", "Set the output file name, '-' for stdout")
+	flags.StringSlice("tables", nil, "Only map these tables")
+	flags.Bool("force", false, "Overwrite the output file")
+
+	RootCmd.AddCommand(ReverseCmd)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/slicebit/qb"
+	_ "github.com/slicebit/qb/dialects/mysql"
+	"github.com/spf13/cobra"
+
+	"github.com/orus-io/yago/generate"
+	"github.com/orus-io/yago/schema"
+)
+
+// SchemaCmd prints the DDL of the mapped structs of a package
+var SchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the DDL of the mapped structs of a package",
+	Long: `Print the CREATE TABLE and CREATE INDEX statements of the yago structs
+of a package, the referenced tables first.
+
+The structs are read from the go files of --dir, the tests excepted.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		flags := cmd.Flags()
+		driver, _ := flags.GetString("dialect")
+		dir, _ := flags.GetString("dir")
+		prefix, _ := flags.GetString("prefix")
+
+		switch driver {
+		case "sqlite3", "postgres", "mysql":
+		default:
+			logger.Fatalf("Unsupported dialect '%s'", driver)
+		}
+
+		structs, err := generate.LoadPackage(dir)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		var tables []qb.TableElem
+		for _, str := range structs {
+			table, err := str.Table()
+			if err != nil {
+				logger.Fatalf("%s: %s", str.Name, err)
+			}
+			tables = append(tables, table)
+		}
+
+		dialect := qb.NewDialect(driver)
+		for _, stmt := range schema.FromTables(dialect, prefix, tables...).DDL(dialect) {
+			fmt.Printf("%s;\n\n", stmt)
+		}
+	},
+}
+
+func init() {
+	flags := SchemaCmd.Flags()
+	flags.String("dialect", "postgres", "Set the SQL dialect (sqlite3, postgres or mysql)")
+	flags.String("dir", ".", "Set the package directory")
+	flags.String("prefix", "", "Set the table prefix")
+
+	RootCmd.AddCommand(SchemaCmd)
+}
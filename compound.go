@@ -0,0 +1,135 @@
+package yago
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/slicebit/qb"
+)
+
+// compoundStmt is a UNION, INTERSECT or EXCEPT of select statements
+type compoundStmt struct {
+	selects   []qb.SelectStmt
+	operators []string
+}
+
+// Accept compiles the compound statement
+func (s compoundStmt) Accept(context *qb.CompilerContext) string {
+	var lines []string
+	for i, sel := range s.selects {
+		if i != 0 {
+			lines = append(lines, s.operators[i-1])
+		}
+		lines = append(lines, compileSelect(context, sel))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// add returns a copy of the compound statement with sel appended
+func (s compoundStmt) add(operator string, sel qb.SelectStmt) *compoundStmt {
+	s.selects = append(s.selects[:len(s.selects):len(s.selects)], sel)
+	s.operators = append(s.operators[:len(s.operators):len(s.operators)], operator)
+	return &s
+}
+
+// selectListType returns the type name of a select list item, or "" if
+// it cannot be guessed
+func selectListType(clause qb.Clause) string {
+	switch c := clause.(type) {
+	case qb.ColumnElem:
+		return c.Type.Name
+	case ScalarField:
+		return c.Column.Type.Name
+	case MarshaledScalarField:
+		return c.Column.Type.Name
+	}
+	return ""
+}
+
+// checkCompatibleSelectLists makes sure two select lists can be combined
+func checkCompatibleSelectLists(left []qb.Clause, right []qb.Clause) error {
+	if len(left) != len(right) {
+		return fmt.Errorf(
+			"yago Query: Cannot combine queries selecting %d and %d columns",
+			len(left), len(right))
+	}
+	for i := range left {
+		leftType := selectListType(left[i])
+		rightType := selectListType(right[i])
+		if leftType != "" && rightType != "" && leftType != rightType {
+			return fmt.Errorf(
+				"yago Query: Cannot combine queries, column %d types differ (%s and %s)",
+				i+1, leftType, rightType)
+		}
+	}
+	return nil
+}
+
+// combine builds a compound query. The resulting query is scanned with the
+// mapper of q.
+func (q Query) combine(method string, operator string, other Query) Query {
+	if q.err != nil {
+		return q
+	}
+	if other.err != nil {
+		return q.setErr(other.err)
+	}
+	if other.compound != nil {
+		return q.setErr(fmt.Errorf(
+			"yago Query.%s(): Cannot combine with a compound query", method))
+	}
+	// the ORDER BY, LIMIT and lock of a compound query apply to the whole
+	// compound, they cannot be set on the combined queries
+	if q.compound == nil && q.hasSelectClauses() || other.hasSelectClauses() {
+		return q.setErr(fmt.Errorf(
+			"yago Query.%s(): Cannot combine queries that have ORDER BY, LIMIT, OFFSET or FOR UPDATE/SHARE clauses",
+			method))
+	}
+	if err := checkCompatibleSelectLists(
+		q.selectStmt.SelectList, other.selectStmt.SelectList,
+	); err != nil {
+		return q.setErr(err)
+	}
+	if q.compound == nil {
+		q.compound = &compoundStmt{selects: []qb.SelectStmt{q.selectStmt}}
+	}
+	q.compound = q.compound.add(operator, other.selectStmt)
+	q.related = append(q.related[:len(q.related):len(q.related)], other.readTables()...)
+	return q
+}
+
+// hasSelectClauses returns true if the query has ORDER BY, LIMIT, OFFSET or
+// lock clauses
+func (q Query) hasSelectClauses() bool {
+	return len(q.orderBy) != 0 || q.limit != nil || q.offset != 0 || q.lock != nil
+}
+
+// Union returns a query that is the UNION of q and other. Both queries must
+// select compatible columns.
+// ORDER BY, LIMIT and locks can be applied to the resulting query only,
+// and Where, Filter and joins to the combined queries only.
+func (q Query) Union(other Query) Query {
+	return q.combine("Union", "UNION", other)
+}
+
+// UnionAll returns a query that is the UNION ALL of q and other
+func (q Query) UnionAll(other Query) Query {
+	return q.combine("UnionAll", "UNION ALL", other)
+}
+
+// Intersect returns a query that is the INTERSECT of q and other
+func (q Query) Intersect(other Query) Query {
+	return q.combine("Intersect", "INTERSECT", other)
+}
+
+// Except returns a query that is the EXCEPT of q and other
+func (q Query) Except(other Query) Query {
+	return q.combine("Except", "EXCEPT", other)
+}
+
+// compoundErr returns an error for a method that cannot be applied to a
+// compound query
+func compoundErr(method string) error {
+	return fmt.Errorf(
+		"yago Query.%s(): Cannot be applied to a compound query", method)
+}
@@ -0,0 +1,107 @@
+package yago_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/orus-io/yago"
+)
+
+func TestCompoundQuery(t *testing.T) {
+	db, model, cleanup := initModel(t)
+	defer cleanup()
+
+	for _, name := range []string{"John", "Jane", "Malcom"} {
+		assert.Nil(t, db.Insert(&PersonStruct{FirstName: name}))
+	}
+
+	all := db.Query(model.PersonStruct)
+	john := db.Query(model.PersonStruct).Where(model.PersonStruct.FirstName.Eq("John"))
+	jane := db.Query(model.PersonStruct).Where(model.PersonStruct.FirstName.Eq("Jane"))
+
+	var persons []PersonStruct
+
+	assert.Nil(t, john.Union(jane).All(&persons))
+	assert.Len(t, persons, 2)
+
+	assert.Nil(t, john.UnionAll(john).All(&persons))
+	assert.Len(t, persons, 2)
+
+	assert.Nil(t, all.Intersect(john).All(&persons))
+	assert.Len(t, persons, 1)
+	assert.Equal(t, "John", persons[0].FirstName)
+
+	assert.Nil(t, all.Except(john).All(&persons))
+	assert.Len(t, persons, 2)
+
+	var p PersonStruct
+	assert.Nil(t, all.Except(john).
+		OrderBy(model.PersonStruct.FirstName).
+		Limit(0, 1).
+		One(&p))
+	assert.Equal(t, "Jane", p.FirstName)
+
+	// sqlite refuses the 'IS NULL' terms in the ORDER BY of a compound
+	// statement, they order a derived table
+	_, err := db.Engine.DB().Exec("UPDATE person_struct SET last_name = 'Doe' WHERE first_name = 'Jane'")
+	assert.Nil(t, err)
+	sql, _, err := all.Except(john).OrderBy(model.PersonStruct.LastName.NullsFirst()).ToSQL()
+	assert.Nil(t, err)
+	assert.Contains(t, sql, "SELECT *\nFROM (")
+	assert.Contains(t, sql, "ORDER BY last_name IS NULL DESC, last_name ASC")
+	assert.Nil(t, all.Except(john).
+		OrderBy(model.PersonStruct.LastName.NullsFirst()).
+		All(&persons))
+	if assert.Len(t, persons, 2) {
+		assert.Equal(t, "Malcom", persons[0].FirstName)
+		assert.Equal(t, "Jane", persons[1].FirstName)
+	}
+	assert.Nil(t, all.Except(john).
+		OrderBy(model.PersonStruct.LastName.Desc().NullsLast()).
+		All(&persons))
+	if assert.Len(t, persons, 2) {
+		assert.Equal(t, "Jane", persons[0].FirstName)
+		assert.Equal(t, "Malcom", persons[1].FirstName)
+	}
+
+	var count int
+	assert.Nil(t, john.Union(jane).Count(&count))
+	assert.Equal(t, 2, count)
+
+	exists, err := john.Intersect(jane).Exists()
+	assert.Nil(t, err)
+	assert.False(t, exists)
+}
+
+func TestCompoundQueryErrors(t *testing.T) {
+	db, model, cleanup := initModel(t)
+	defer cleanup()
+
+	q := db.Query(model.PersonStruct)
+
+	// Incompatible select lists
+	assert.NotNil(t, q.Union(q.Select(model.PersonStruct.FirstName)).Err())
+
+	// Where must be applied before combining the queries
+	assert.NotNil(t, q.Union(q).Where(model.PersonStruct.FirstName.Eq("John")).Err())
+
+	// Nested compound queries are not supported
+	assert.NotNil(t, q.Union(q.Union(q)).Err())
+
+	// ORDER BY, LIMIT and locks would apply to the whole compound query
+	ordered := q.OrderBy(model.PersonStruct.FirstName)
+	for _, combined := range []yago.Query{
+		ordered.Union(q),
+		q.Union(ordered),
+		q.Limit(0, 1).Union(q),
+		q.UnionAll(q.Limit(1, 1)),
+		q.ForUpdate().Intersect(q),
+		q.Except(q.ForShare()),
+	} {
+		if assert.NotNil(t, combined.Err()) {
+			assert.Contains(t, combined.Err().Error(), "Cannot combine queries that have ORDER BY")
+		}
+	}
+	assert.Nil(t, q.Union(q).OrderBy(model.PersonStruct.FirstName).Limit(0, 1).Err())
+}
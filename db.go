@@ -11,8 +11,13 @@ import (
 type IDB interface {
 	Insert(MappedStruct) error
 	Update(MappedStruct, ...string) error
+	UpdateExpr(MappedStruct, ...Assignment) error
 	Delete(MappedStruct) error
+	InsertWith(MapperProvider, MappedStruct) error
+	UpdateWith(MapperProvider, MappedStruct, ...string) error
+	DeleteWith(MapperProvider, MappedStruct) error
 	Query(MapperProvider) Query
+	RawQuery(mp MapperProvider, sql string, args ...interface{}) Query
 
 	GetEngine() Engine
 }
@@ -24,12 +29,16 @@ type Engine interface {
 	QueryRow(builder qb.Builder) qb.Row
 }
 
-// New initialise a new DB
+// New initialise a new DB. It uses a copy of the engine, which dialect is
+// wrapped so the table names are qualified with the metadata schema and
+// table prefix. The copy shares the connection pool of engine.
 func New(metadata *Metadata, engine *qb.Engine) *DB {
+	e := *engine
+	e.SetDialect(newNamingDialect(engine.Dialect(), metadata, nil))
 	return &DB{
-		metadata,
-		engine,
-		DefaultCallbacks,
+		Metadata:  metadata,
+		Engine:    &e,
+		Callbacks: DefaultCallbacks,
 	}
 }
 
@@ -38,6 +47,8 @@ type DB struct {
 	Metadata  *Metadata
 	Engine    *qb.Engine
 	Callbacks Callbacks
+
+	cache *queryCache
 }
 
 // GetEngine returns the underlying engine
@@ -47,22 +58,76 @@ func (db DB) GetEngine() Engine {
 
 // Insert a struct in the database
 func (db *DB) Insert(s MappedStruct) error {
-	return db.doInsert(db.Engine, s)
+	mapper, err := db.Metadata.LookupMapper(s)
+	if err != nil {
+		return err
+	}
+	return db.afterWrite(db.doInsert(db.Engine, mapper, s), mapper)
+}
+
+// InsertWith inserts a struct in the database with a given mapper
+func (db *DB) InsertWith(mp MapperProvider, s MappedStruct) error {
+	mapper, err := checkMapper("InsertWith", mp, s)
+	if err != nil {
+		return err
+	}
+	return db.afterWrite(db.doInsert(db.Engine, mapper, s), mapper)
 }
 
 // Update the struct attributes in DB
 func (db *DB) Update(s MappedStruct, fields ...string) error {
-	return db.doUpdate(db.Engine, s, fields...)
+	mapper, err := db.Metadata.LookupMapper(s)
+	if err != nil {
+		return err
+	}
+	return db.afterWrite(db.doUpdate(db.Engine, mapper, s, fields...), mapper)
+}
+
+// UpdateWith updates the struct attributes in DB with a given mapper
+func (db *DB) UpdateWith(mp MapperProvider, s MappedStruct, fields ...string) error {
+	mapper, err := checkMapper("UpdateWith", mp, s)
+	if err != nil {
+		return err
+	}
+	return db.afterWrite(db.doUpdate(db.Engine, mapper, s, fields...), mapper)
+}
+
+// UpdateExpr atomically applies assignments to the struct record, and
+// loads the resulting values in the struct. The assignments values can be
+// expressions, for example:
+//
+//	db.UpdateExpr(&post, yago.Set(model.Post.Views, model.Post.Views.Add(1)))
+//
+// Only the assigned columns, and the columns set by the BeforeUpdate
+// callbacks, are written. On other dialects than postgres, the update and the
+// reload of the record run in a transaction.
+func (db *DB) UpdateExpr(s MappedStruct, assignments ...Assignment) error {
+	mapper, err := db.Metadata.LookupMapper(s)
+	if err != nil {
+		return err
+	}
+	return db.afterWrite(db.doUpdateExpr(db, mapper, s, assignments...), mapper)
 }
 
 // Delete a struct in the database
 func (db *DB) Delete(s MappedStruct) error {
-	return db.doDelete(db.Engine, s)
+	mapper, err := db.Metadata.LookupMapper(s)
+	if err != nil {
+		return err
+	}
+	return db.afterWrite(db.doDelete(db.Engine, mapper, s), mapper)
 }
 
-func (db *DB) doInsertWithReturning(engine Engine, s MappedStruct) error {
-	mapper := db.Metadata.GetMapper(s)
+// DeleteWith deletes a struct in the database with a given mapper
+func (db *DB) DeleteWith(mp MapperProvider, s MappedStruct) error {
+	mapper, err := checkMapper("DeleteWith", mp, s)
+	if err != nil {
+		return err
+	}
+	return db.afterWrite(db.doDelete(db.Engine, mapper, s), mapper)
+}
 
+func (db *DB) doInsertWithReturning(engine Engine, mapper Mapper, s MappedStruct) error {
 	insert := mapper.Table().Insert().
 		Values(mapper.SQLValues(s)).
 		Returning(mapper.Table().PrimaryCols()...)
@@ -88,17 +153,26 @@ func (db *DB) doInsertWithReturning(engine Engine, s MappedStruct) error {
 	return nil
 }
 
+// checkMapper returns the mapper of mp if it maps the struct
+func checkMapper(method string, mp MapperProvider, s MappedStruct) (Mapper, error) {
+	mapper := mp.GetMapper()
+	if mapper.StructType() != s.StructType() {
+		return nil, fmt.Errorf("yago %s: Mapper '%s' cannot map a %s",
+			method, mapper.Name(), s.StructType())
+	}
+	return mapper, nil
+}
+
 // Close closes the underlying db connection
 func (db *DB) Close() error {
 	return db.Engine.Close()
 }
 
-func (db *DB) doInsert(engine Engine, s MappedStruct) error {
+func (db *DB) doInsert(engine Engine, mapper Mapper, s MappedStruct) error {
 	db.Callbacks.BeforeInsert.Call(db, s)
-	mapper := db.Metadata.GetMapper(s)
 
 	if mapper.AutoIncrementPKey() && db.Engine.Dialect().Driver() == "postgres" {
-		return db.doInsertWithReturning(engine, s)
+		return db.doInsertWithReturning(engine, mapper, s)
 	}
 
 	insert := mapper.Table().Insert().Values(mapper.SQLValues(s))
@@ -126,9 +200,8 @@ func (db *DB) doInsert(engine Engine, s MappedStruct) error {
 	return nil
 }
 
-func (db *DB) doUpdate(engine Engine, s MappedStruct, fields ...string) error {
+func (db *DB) doUpdate(engine Engine, mapper Mapper, s MappedStruct, fields ...string) error {
 	db.Callbacks.BeforeUpdate.Call(db, s)
-	mapper := db.Metadata.GetMapper(s)
 	update := mapper.Table().Update().
 		Values(mapper.SQLValues(s, fields...)).
 		Where(mapper.PKeyClause(mapper.PKey(s)))
@@ -152,14 +225,19 @@ func (db *DB) doUpdate(engine Engine, s MappedStruct, fields ...string) error {
 
 // Query returns a new Query for the struct
 func (db *DB) Query(mp MapperProvider) Query {
-	mapper := mp.GetMapper()
-	return NewQuery(db, mapper)
+	return newModelQuery(db, mp)
+}
+
+// RawQuery returns a new Query that loads structs from a raw SQL select
+func (db *DB) RawQuery(mp MapperProvider, sql string, args ...interface{}) Query {
+	q := NewRawQuery(db, mp.GetMapper(), sql, args...)
+	q.model = mp
+	return q
 }
 
 // Delete a struct from the database
-func (db *DB) doDelete(engine Engine, s MappedStruct) error {
+func (db *DB) doDelete(engine Engine, mapper Mapper, s MappedStruct) error {
 	db.Callbacks.BeforeDelete.Call(db, s)
-	mapper := db.Metadata.GetMapper(s)
 	del := mapper.Table().Delete().Where(mapper.PKeyClause(mapper.PKey(s)))
 	res, err := engine.Exec(del)
 	if err != nil {
@@ -184,13 +262,16 @@ func (db *DB) Begin() (*Tx, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Tx{db: db, tx: tx}, nil
+	return &Tx{db: db, tx: tx, written: new([]string)}, nil
 }
 
 // Tx is an on-going database transaction
 type Tx struct {
 	db *DB
 	tx *qb.Tx
+
+	// written are the tables to invalidate in the cache on Commit
+	written *[]string
 }
 
 // GetEngine returns the underlying qb.Tx
@@ -200,31 +281,94 @@ func (tx Tx) GetEngine() Engine {
 
 // Insert a new struct to the database
 func (tx Tx) Insert(s MappedStruct) error {
-	return tx.db.doInsert(tx.tx, s)
+	mapper, err := tx.db.Metadata.LookupMapper(s)
+	if err != nil {
+		return err
+	}
+	return tx.afterWrite(tx.db.doInsert(tx.tx, mapper, s), mapper)
+}
+
+// InsertWith inserts a new struct to the database with a given mapper
+func (tx Tx) InsertWith(mp MapperProvider, s MappedStruct) error {
+	mapper, err := checkMapper("InsertWith", mp, s)
+	if err != nil {
+		return err
+	}
+	return tx.afterWrite(tx.db.doInsert(tx.tx, mapper, s), mapper)
 }
 
 // Update write struct values to the database
 // If fields is provided, only theses fields are written
 func (tx Tx) Update(s MappedStruct, fields ...string) error {
-	return tx.db.doUpdate(tx.tx, s, fields...)
+	mapper, err := tx.db.Metadata.LookupMapper(s)
+	if err != nil {
+		return err
+	}
+	return tx.afterWrite(tx.db.doUpdate(tx.tx, mapper, s, fields...), mapper)
+}
+
+// UpdateWith write struct values to the database with a given mapper
+func (tx Tx) UpdateWith(mp MapperProvider, s MappedStruct, fields ...string) error {
+	mapper, err := checkMapper("UpdateWith", mp, s)
+	if err != nil {
+		return err
+	}
+	return tx.afterWrite(tx.db.doUpdate(tx.tx, mapper, s, fields...), mapper)
+}
+
+// UpdateExpr atomically applies assignments to the struct record, and
+// loads the resulting values in the struct
+func (tx Tx) UpdateExpr(s MappedStruct, assignments ...Assignment) error {
+	mapper, err := tx.db.Metadata.LookupMapper(s)
+	if err != nil {
+		return err
+	}
+	return tx.afterWrite(tx.db.doUpdateExpr(tx, mapper, s, assignments...), mapper)
 }
 
 // Delete drop a struct from the database
 func (tx Tx) Delete(s MappedStruct) error {
-	return tx.db.doDelete(tx.tx, s)
+	mapper, err := tx.db.Metadata.LookupMapper(s)
+	if err != nil {
+		return err
+	}
+	return tx.afterWrite(tx.db.doDelete(tx.tx, mapper, s), mapper)
+}
+
+// DeleteWith drop a struct from the database with a given mapper
+func (tx Tx) DeleteWith(mp MapperProvider, s MappedStruct) error {
+	mapper, err := checkMapper("DeleteWith", mp, s)
+	if err != nil {
+		return err
+	}
+	return tx.afterWrite(tx.db.doDelete(tx.tx, mapper, s), mapper)
 }
 
 // Query returns a new Query
 func (tx Tx) Query(mp MapperProvider) Query {
-	return NewQuery(tx, mp.GetMapper())
+	return newModelQuery(tx, mp)
+}
+
+// RawQuery returns a new Query that loads structs from a raw SQL select
+func (tx Tx) RawQuery(mp MapperProvider, sql string, args ...interface{}) Query {
+	q := NewRawQuery(tx, mp.GetMapper(), sql, args...)
+	q.model = mp
+	return q
 }
 
-// Commit commits the transaction
+// Commit commits the transaction, and invalidates the cached results that
+// read the tables written by the transaction
 func (tx Tx) Commit() error {
-	return tx.tx.Commit()
+	if err := tx.tx.Commit(); err != nil {
+		return err
+	}
+	tx.db.invalidateTables(*tx.written...)
+	*tx.written = nil
+	return nil
 }
 
 // Rollback aborts the transaction
 func (tx Tx) Rollback() error {
+	*tx.written = nil
 	return tx.tx.Rollback()
 }
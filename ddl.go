@@ -0,0 +1,194 @@
+package yago
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/slicebit/qb"
+
+	"github.com/orus-io/yago/schema"
+)
+
+// TableGraph is the foreign key dependency graph of the tables of a
+// Metadata
+type TableGraph struct {
+	// Tables are the table names, the referenced tables first, but for the
+	// foreign keys listed in Cycles
+	Tables []string
+	// Dependencies maps each table to the tables it references, itself
+	// excluded
+	Dependencies map[string][]string
+	// Cycles are the foreign keys that reference a table that comes later
+	// in Tables, closing a dependency cycle
+	Cycles []schema.TableForeignKey
+}
+
+// HasCycles returns true if some foreign keys close a dependency cycle
+func (g *TableGraph) HasCycles() bool {
+	return len(g.Cycles) != 0
+}
+
+// TableGraph returns the foreign key dependency graph of the tables, as
+// ordered by DDL
+func (m *Metadata) TableGraph() *TableGraph {
+	var tables []*schema.Table
+	for _, table := range m.qbMeta.Tables() {
+		t := &schema.Table{Name: table.Name}
+		for _, fk := range table.ForeignKeyConstraints.FKeys {
+			t.ForeignKeys = append(t.ForeignKeys, schema.ForeignKey{
+				Columns: fk.Cols, RefTable: fk.RefTable, RefColumns: fk.RefCols,
+			})
+		}
+		tables = append(tables, t)
+	}
+	sorted, cycles := schema.SortByDependencies(tables)
+
+	g := &TableGraph{Dependencies: make(map[string][]string), Cycles: cycles}
+	known := make(map[string]bool)
+	for _, t := range sorted {
+		g.Tables = append(g.Tables, t.Name)
+		known[t.Name] = true
+	}
+	for _, t := range sorted {
+		for _, fk := range t.ForeignKeys {
+			if known[fk.RefTable] && fk.RefTable != t.Name {
+				g.Dependencies[t.Name] = appendUnique(g.Dependencies[t.Name], fk.RefTable)
+			}
+		}
+	}
+	return g
+}
+
+func appendUnique(list []string, value string) []string {
+	for _, v := range list {
+		if v == value {
+			return list
+		}
+	}
+	return append(list, value)
+}
+
+// ddlTx runs statements in a transaction, with the foreign key checks
+// deferred on sqlite and disabled on mysql if deferFKs is true
+func ddlTx(engine *qb.Engine, deferFKs bool, fn func(tx *sql.Tx) error) error {
+	tx, err := engine.DB().Begin()
+	if err != nil {
+		return err
+	}
+	if deferFKs {
+		switch engine.Dialect().Driver() {
+		case "sqlite3":
+			_, err = tx.Exec("PRAGMA defer_foreign_keys = ON")
+		case "mysql":
+			_, err = tx.Exec("SET FOREIGN_KEY_CHECKS = 0")
+		}
+	}
+	if err == nil {
+		err = fn(tx)
+	}
+	if deferFKs && engine.Dialect().Driver() == "mysql" {
+		// the setting belongs to the connection, which goes back to the pool
+		if _, resetErr := tx.Exec("SET FOREIGN_KEY_CHECKS = 1"); err == nil {
+			err = resetErr
+		}
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// CreateAll creates the tables and their indexes, with the statements of
+// DDL. If ifNotExists is true, the existing tables are skipped.
+func (m *Metadata) CreateAll(engine *qb.Engine, ifNotExists bool) error {
+	dialect := newNamingDialect(engine.Dialect(), m, nil)
+	existing := make(map[string]bool)
+	if ifNotExists {
+		names, err := schema.TableNames(engine.DB(), engine.Dialect().Driver(), m.schema)
+		if err != nil {
+			return fmt.Errorf("yago Metadata.CreateAll(): Cannot list the tables: %s", err)
+		}
+		for _, name := range names {
+			existing[name] = true
+		}
+	}
+	var tables []qb.TableElem
+	for _, table := range m.qbMeta.Tables() {
+		if !existing[m.tablePrefix+table.Name] {
+			tables = append(tables, table)
+		}
+	}
+	return ddlTx(engine, false, func(tx *sql.Tx) error {
+		for _, stmt := range m.schemaOf(dialect.Dialect, tables...).DDL(dialect.Dialect) {
+			if _, err := tx.Exec(stmt); err != nil {
+				return fmt.Errorf("yago Metadata.CreateAll(): Cannot run '%s': %s",
+					strings.SplitN(stmt, "\n", 2)[0], err)
+			}
+		}
+		return nil
+	})
+}
+
+// DropAll drops the tables, the referencing tables first. If ifExists is
+// true, the missing tables are ignored.
+// If the foreign keys have cycles, the checks are deferred on sqlite and
+// disabled on mysql, and the tables are dropped with CASCADE on postgres.
+func (m *Metadata) DropAll(engine *qb.Engine, ifExists bool) error {
+	dialect := newNamingDialect(engine.Dialect(), m, nil)
+	g := m.TableGraph()
+	drop := "DROP TABLE "
+	if ifExists {
+		drop += "IF EXISTS "
+	}
+	cascade := ""
+	if g.HasCycles() && engine.Dialect().Driver() == "postgres" {
+		cascade = " CASCADE"
+	}
+	return ddlTx(engine, g.HasCycles(), func(tx *sql.Tx) error {
+		for i := len(g.Tables) - 1; i >= 0; i-- {
+			name := g.Tables[i]
+			if _, err := tx.Exec(drop + dialect.qualify(name) + cascade); err != nil {
+				return fmt.Errorf("yago Metadata.DropAll(): Cannot drop table '%s': %s", name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// TruncateAll deletes all the rows of the tables, the referencing tables
+// first. On postgres, the sequences are restarted.
+func (m *Metadata) TruncateAll(engine *qb.Engine) error {
+	dialect := newNamingDialect(engine.Dialect(), m, nil)
+	g := m.TableGraph()
+	if len(g.Tables) == 0 {
+		return nil
+	}
+	driver := engine.Dialect().Driver()
+	// mysql refuses to truncate a referenced table, even an empty one
+	return ddlTx(engine, g.HasCycles() || driver == "mysql", func(tx *sql.Tx) error {
+		if driver == "postgres" {
+			var tables []string
+			for _, name := range g.Tables {
+				tables = append(tables, dialect.qualify(name))
+			}
+			_, err := tx.Exec("TRUNCATE " + strings.Join(tables, ", ") + " RESTART IDENTITY")
+			if err != nil {
+				return fmt.Errorf("yago Metadata.TruncateAll(): %s", err)
+			}
+			return nil
+		}
+		stmt := "DELETE FROM "
+		if driver == "mysql" {
+			stmt = "TRUNCATE TABLE "
+		}
+		for i := len(g.Tables) - 1; i >= 0; i-- {
+			name := g.Tables[i]
+			if _, err := tx.Exec(stmt + dialect.qualify(name)); err != nil {
+				return fmt.Errorf("yago Metadata.TruncateAll(): Cannot truncate table '%s': %s", name, err)
+			}
+		}
+		return nil
+	})
+}
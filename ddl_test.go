@@ -0,0 +1,105 @@
+package yago_test
+
+import (
+	"testing"
+
+	"github.com/slicebit/qb"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/orus-io/yago"
+	"github.com/orus-io/yago/schema"
+)
+
+func TestTableGraph(t *testing.T) {
+	meta := yago.NewMetadata()
+	NewAutoIncChildModel(meta)
+	NewSimpleStructModel(meta)
+	NewPersonStructModel(meta)
+
+	g := meta.TableGraph()
+	assert.Equal(t, []string{"person_struct", "auto_inc_child", "simple_struct"}, g.Tables)
+	assert.Equal(t, map[string][]string{"auto_inc_child": {"person_struct"}}, g.Dependencies)
+	assert.False(t, g.HasCycles())
+}
+
+// addCycle registers two tables that reference each other
+func addCycle(meta *yago.Metadata) {
+	meta.GetQbMetadata().AddTable(qb.Table("node_a",
+		qb.Column("id", qb.BigInt()).PrimaryKey().NotNull(),
+		qb.Column("b_id", qb.BigInt()).Null(),
+		qb.ForeignKey("b_id").References("node_b", "id"),
+	))
+	meta.GetQbMetadata().AddTable(qb.Table("node_b",
+		qb.Column("id", qb.BigInt()).PrimaryKey().NotNull(),
+		qb.Column("a_id", qb.BigInt()).NotNull(),
+		qb.ForeignKey("a_id").References("node_a", "id"),
+	))
+}
+
+func TestCreateDropAll(t *testing.T) {
+	engine := initSqliteEngine(t)
+	defer engine.Close()
+	_, err := engine.DB().Exec("PRAGMA foreign_keys = ON")
+	assert.Nil(t, err)
+
+	meta := yago.NewMetadata()
+	NewAutoIncChildModel(meta)
+	NewPersonStructModel(meta)
+	addCycle(meta)
+
+	g := meta.TableGraph()
+	assert.Equal(t, []string{"person_struct", "auto_inc_child", "node_b", "node_a"}, g.Tables)
+	if assert.True(t, g.HasCycles()) {
+		assert.Equal(t, "node_b", g.Cycles[0].Table)
+		assert.Equal(t, "node_a", g.Cycles[0].RefTable)
+	}
+
+	assert.Nil(t, meta.CreateAll(engine, false))
+	assert.NotNil(t, meta.CreateAll(engine, false))
+	assert.Nil(t, meta.CreateAll(engine, true))
+
+	// the foreign key actions are created
+	current, err := schema.Inspect(engine.DB(), "sqlite3", "")
+	assert.Nil(t, err)
+	if fks := current.Table("auto_inc_child").ForeignKeys; assert.Len(t, fks, 1) {
+		assert.Equal(t, "CASCADE", fks[0].OnUpdate)
+		assert.Equal(t, "SET NULL", fks[0].OnDelete)
+	}
+
+	db := yago.New(meta, engine)
+	p := PersonStruct{FirstName: "John"}
+	assert.Nil(t, db.Insert(&p))
+	assert.Nil(t, db.Insert(&AutoIncChild{Name: "child", Person: p.ID}))
+	for _, stmt := range []string{
+		"INSERT INTO node_a (id, b_id) VALUES (1, NULL)",
+		"INSERT INTO node_b (id, a_id) VALUES (1, 1)",
+		"UPDATE node_a SET b_id = 1",
+	} {
+		_, err := engine.DB().Exec(stmt)
+		assert.Nil(t, err)
+	}
+
+	assert.Nil(t, meta.TruncateAll(engine))
+	for _, table := range g.Tables {
+		var count int
+		assert.Nil(t, engine.DB().QueryRow("SELECT COUNT(*) FROM "+table).Scan(&count))
+		assert.Equal(t, 0, count, table)
+	}
+
+	assert.Nil(t, db.Insert(&PersonStruct{FirstName: "Jane"}))
+	assert.Nil(t, meta.DropAll(engine, false))
+	assert.NotNil(t, meta.DropAll(engine, false))
+	assert.Nil(t, meta.DropAll(engine, true))
+}
+
+func TestCreateAllListError(t *testing.T) {
+	engine := initSqliteEngine(t)
+	meta := yago.NewMetadata()
+	NewPersonStructModel(meta)
+	engine.Close()
+
+	err := meta.CreateAll(engine, true)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "Cannot list the tables")
+	}
+}
@@ -0,0 +1,285 @@
+package yago
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/slicebit/qb"
+)
+
+// dialectOf returns the dialect of a DB or Tx
+func dialectOf(db IDB) qb.Dialect {
+	switch d := db.(type) {
+	case *DB:
+		return d.Engine.Dialect()
+	case *Tx:
+		return d.db.Engine.Dialect()
+	case Tx:
+		return d.db.Engine.Dialect()
+	}
+	if e, ok := db.GetEngine().(interface{ Dialect() qb.Dialect }); ok {
+		return e.Dialect()
+	}
+	return qb.NewDialect("default")
+}
+
+// Dialect returns the dialect of the query DB
+func (q Query) Dialect() qb.Dialect {
+	return dialectOf(q.db)
+}
+
+// ToSQL returns the SQL and the binds of the query, compiled for the
+// dialect of its DB
+func (q Query) ToSQL() (string, []interface{}, error) {
+	if q.err != nil {
+		return "", nil, q.err
+	}
+	dialect := dialectOf(q.db)
+	if err := q.lock.check(dialect); err != nil {
+		return "", nil, err
+	}
+	stmt := q.statement().Build(dialect)
+	return stmt.SQL(), stmt.Bindings(), nil
+}
+
+// DebugString returns the SQL of the query with the binds interpolated, for
+// debugging purpose only: the result must not be run.
+func (q Query) DebugString() string {
+	sql, binds, err := q.ToSQL()
+	if err != nil {
+		return fmt.Sprintf("<error: %s>", err)
+	}
+	return interpolateBinds(sql, binds)
+}
+
+// interpolateBinds replaces the '?' and '$n' placeholders of a SQL query
+// with their values. The string literals and quoted identifiers are left
+// untouched.
+func interpolateBinds(sql string, binds []interface{}) string {
+	var b strings.Builder
+	next := 0
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			end := strings.IndexByte(sql[i+1:], c)
+			if end == -1 {
+				b.WriteString(sql[i:])
+				return b.String()
+			}
+			b.WriteString(sql[i : i+end+2])
+			i += end + 1
+		case c == '?' && next < len(binds):
+			b.WriteString(formatBind(binds[next]))
+			next++
+		case c == '$' && i+1 < len(sql) && sql[i+1] >= '0' && sql[i+1] <= '9':
+			j := i + 1
+			for j < len(sql) && sql[j] >= '0' && sql[j] <= '9' {
+				j++
+			}
+			n, _ := strconv.Atoi(sql[i+1 : j])
+			if n < 1 || n > len(binds) {
+				b.WriteString(sql[i:j])
+			} else {
+				b.WriteString(formatBind(binds[n-1]))
+			}
+			i = j - 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// formatBind formats a bind value as a SQL literal
+func formatBind(value interface{}) string {
+	if v, ok := value.(driver.Valuer); ok {
+		dv, err := v.Value()
+		if err != nil {
+			return fmt.Sprintf("<error: %s>", err)
+		}
+		value = dv
+	}
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return "'" + strings.Replace(v, "'", "''", -1) + "'"
+	case []byte:
+		if utf8.Valid(v) {
+			return formatBind(string(v))
+		}
+		return "X'" + hex.EncodeToString(v) + "'"
+	case bool:
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64,
+		float32, float64:
+		return fmt.Sprint(v)
+	case time.Time:
+		return "'" + v.Format("2006-01-02 15:04:05.999999999-07:00") + "'"
+	default:
+		return formatBind(fmt.Sprint(v))
+	}
+}
+
+// PlanNode is a node of a query plan
+type PlanNode struct {
+	// Detail describes the node: the sqlite plan detail, or the postgres
+	// node type and relation
+	Detail string
+	// Properties are the postgres node properties (costs, rows, timings...)
+	Properties map[string]interface{}
+	Children   []*PlanNode
+}
+
+// QueryPlan is the plan of a query, as returned by Explain
+type QueryPlan struct {
+	Nodes []*PlanNode
+	// Properties are the postgres plan properties (planning and execution
+	// times...)
+	Properties map[string]interface{}
+}
+
+// String returns the plan as an indented tree
+func (p QueryPlan) String() string {
+	var b strings.Builder
+	var write func(nodes []*PlanNode, depth int)
+	write = func(nodes []*PlanNode, depth int) {
+		for _, node := range nodes {
+			b.WriteString(strings.Repeat("  ", depth) + node.Detail + "\n")
+			write(node.Children, depth+1)
+		}
+	}
+	write(p.Nodes, 0)
+	return b.String()
+}
+
+// explainStmt is a EXPLAIN statement
+type explainStmt struct {
+	prefix string
+	query  qb.Clause
+}
+
+// Accept compiles the statement
+func (s explainStmt) Accept(context *qb.CompilerContext) string {
+	return s.prefix + " " + s.query.Accept(context)
+}
+
+// Build compiles the statement for a dialect
+func (s explainStmt) Build(dialect qb.Dialect) *qb.Stmt {
+	return buildStatement(s, dialect)
+}
+
+// Explain returns the plan of the query, on sqlite (EXPLAIN QUERY PLAN)
+// and postgres
+func (q Query) Explain() (QueryPlan, error) {
+	return q.explain("Explain", false)
+}
+
+// ExplainAnalyze runs the query and returns its plan with the actual
+// timings. It is only supported on postgres.
+func (q Query) ExplainAnalyze() (QueryPlan, error) {
+	return q.explain("ExplainAnalyze", true)
+}
+
+func (q Query) explain(method string, analyze bool) (QueryPlan, error) {
+	if q.err != nil {
+		return QueryPlan{}, q.err
+	}
+	dialect := dialectOf(q.db)
+	switch driver := dialect.Driver(); {
+	case isSqlite(dialect):
+		if analyze {
+			return QueryPlan{}, fmt.Errorf(
+				"yago Query.%s(): Not supported by %s", method, driver)
+		}
+		return q.explainSqlite()
+	case driver == "postgres":
+		prefix := "EXPLAIN (FORMAT JSON)"
+		if analyze {
+			prefix = "EXPLAIN (ANALYZE, FORMAT JSON)"
+		}
+		return q.explainPostgres(prefix)
+	default:
+		return QueryPlan{}, fmt.Errorf(
+			"yago Query.%s(): Not supported by %s", method, driver)
+	}
+}
+
+// explainSqlite parses the (id, parent, notused, detail) rows of a EXPLAIN
+// QUERY PLAN
+func (q Query) explainSqlite() (QueryPlan, error) {
+	rows, err := q.db.GetEngine().Query(explainStmt{"EXPLAIN QUERY PLAN", q.statement()})
+	if err != nil {
+		return QueryPlan{}, err
+	}
+	defer rows.Close()
+
+	var plan QueryPlan
+	nodes := make(map[int]*PlanNode)
+	for rows.Next() {
+		var (
+			id, parent, notused int
+			detail              string
+		)
+		if err := rows.Scan(&id, &parent, &notused, &detail); err != nil {
+			return QueryPlan{}, fmt.Errorf("yago Query.Explain(): Error while scanning: %s", err)
+		}
+		node := &PlanNode{Detail: detail}
+		if p, ok := nodes[parent]; ok && parent != 0 {
+			p.Children = append(p.Children, node)
+		} else {
+			plan.Nodes = append(plan.Nodes, node)
+		}
+		nodes[id] = node
+	}
+	return plan, rows.Err()
+}
+
+// explainPostgres parses the result of a EXPLAIN (FORMAT JSON)
+func (q Query) explainPostgres(prefix string) (QueryPlan, error) {
+	var result []byte
+	if err := q.db.GetEngine().QueryRow(explainStmt{prefix, q.statement()}).Scan(&result); err != nil {
+		return QueryPlan{}, err
+	}
+	var plans []map[string]interface{}
+	if err := json.Unmarshal(result, &plans); err != nil {
+		return QueryPlan{}, fmt.Errorf("yago Query.Explain(): Invalid plan: %s", err)
+	}
+	var plan QueryPlan
+	for _, p := range plans {
+		root, _ := p["Plan"].(map[string]interface{})
+		delete(p, "Plan")
+		plan.Properties = p
+		if root != nil {
+			plan.Nodes = append(plan.Nodes, postgresPlanNode(root))
+		}
+	}
+	return plan, nil
+}
+
+// postgresPlanNode converts a postgres JSON plan node
+func postgresPlanNode(properties map[string]interface{}) *PlanNode {
+	node := &PlanNode{Properties: properties}
+	node.Detail, _ = properties["Node Type"].(string)
+	if relation, ok := properties["Relation Name"].(string); ok {
+		node.Detail += " on " + relation
+	}
+	children, _ := properties["Plans"].([]interface{})
+	delete(properties, "Plans")
+	for _, child := range children {
+		if c, ok := child.(map[string]interface{}); ok {
+			node.Children = append(node.Children, postgresPlanNode(c))
+		}
+	}
+	return node
+}
@@ -0,0 +1,64 @@
+package yago_test
+
+import (
+	"testing"
+
+	"github.com/slicebit/qb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToSQL(t *testing.T) {
+	db, model, cleanup := initModel(t)
+	defer cleanup()
+
+	q := db.Query(model.PersonStruct).Select(qb.SQLText("X")).
+		Filter(model.PersonStruct.FirstName.Eq("O'Hara"))
+	sql, binds, err := q.ToSQL()
+	assert.Nil(t, err)
+	assert.Contains(t, sql, "SELECT X\nFROM person_struct\nWHERE ")
+	assert.Contains(t, sql, "first_name = ?")
+	assert.Equal(t, []interface{}{"O'Hara"}, binds)
+
+	_, _, err = db.Query(model.PersonStruct).OrderBy(nil).ToSQL()
+	assert.NotNil(t, err)
+}
+
+func TestDebugString(t *testing.T) {
+	db, model, cleanup := initModel(t)
+	defer cleanup()
+
+	m := model.PersonStruct
+	q := db.Query(m).Select(qb.SQLText("'?'")).Filter(
+		m.FirstName.Eq("O'Hara"),
+		m.Active.Eq(true),
+		m.Gender.Eq(Male),
+	).Limit(0, 1)
+	debug := q.DebugString()
+	assert.Contains(t, debug, "SELECT '?'\n")
+	assert.Contains(t, debug, "first_name = 'O''Hara'")
+	assert.Contains(t, debug, "active = TRUE")
+	assert.Contains(t, debug, "gender = 'male'")
+	assert.NotContains(t, debug, "= ?")
+
+	assert.Equal(t,
+		"<error: yago Query.OrderBy(): Got a nil clause>",
+		db.Query(m).OrderBy(nil).DebugString())
+}
+
+func TestExplain(t *testing.T) {
+	db, model, cleanup := initModel(t)
+	defer cleanup()
+
+	plan, err := db.Query(model.PersonStruct).
+		Filter(model.PersonStruct.FirstName.Eq("John")).
+		Explain()
+	assert.Nil(t, err)
+	if assert.NotEmpty(t, plan.Nodes) {
+		assert.Contains(t, plan.String(), "person_struct")
+	}
+
+	_, err = db.Query(model.PersonStruct).ExplainAnalyze()
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "yago Query.ExplainAnalyze(): Not supported by sqlite3", err.Error())
+	}
+}
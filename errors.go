@@ -16,4 +16,8 @@ var (
 	// ErrInvalidColumns is returned by Scalar if the query returned
 	// a number of columns != 1
 	ErrInvalidColumns = errors.New("yago.InvalidColumns")
+
+	// ErrMapperNotFound is returned by LookupMapper if no mapper is
+	// registered for a struct
+	ErrMapperNotFound = errors.New("yago.MapperNotFound")
 )
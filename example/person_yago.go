@@ -54,7 +54,8 @@ func (Person) StructType() reflect.Type {
 // PersonModel provides direct access to helpers for Person
 // queries
 type PersonModel struct {
-	mapper    *PersonMapper
+	meta      *yago.Metadata
+	mapper    yago.Mapper
 	Name      yago.ScalarField
 	Email     yago.ScalarField
 	ID        yago.ScalarField
@@ -66,7 +67,12 @@ type PersonModel struct {
 func NewPersonModel(meta *yago.Metadata) PersonModel {
 	mapper := NewPersonMapper()
 	meta.AddMapper(mapper)
+	return newPersonModel(meta, mapper)
+}
+
+func newPersonModel(meta *yago.Metadata, mapper yago.Mapper) PersonModel {
 	return PersonModel{
+		meta:      meta,
 		mapper:    mapper,
 		Name:      yago.NewScalarField(mapper.Table().C(PersonNameColumnName)),
 		Email:     yago.NewScalarField(mapper.Table().C(PersonEmailColumnName)),
@@ -81,6 +87,27 @@ func (m PersonModel) GetMapper() yago.Mapper {
 	return m.mapper
 }
 
+// Using returns a PersonModel that uses a named mapper, see
+// yago.Metadata.AddNamedMapper. It panics if there is no such mapper, use
+// Lookup to get an error instead.
+func (m PersonModel) Using(name string) PersonModel {
+	model, err := m.Lookup(name)
+	if err != nil {
+		panic(fmt.Sprintf("Person has no '%s' mapper", name))
+	}
+	return model
+}
+
+// Lookup returns a PersonModel that uses a named mapper, or
+// yago.ErrMapperNotFound
+func (m PersonModel) Lookup(name string) (PersonModel, error) {
+	mapper, err := m.meta.LookupNamedMapper(Person{}, name)
+	if err != nil {
+		return PersonModel{}, err
+	}
+	return newPersonModel(m.meta, mapper), nil
+}
+
 // NewPersonMapper initialize a NewPersonMapper
 func NewPersonMapper() *PersonMapper {
 	m := &PersonMapper{}
@@ -253,7 +280,8 @@ func (PhoneNumber) StructType() reflect.Type {
 // PhoneNumberModel provides direct access to helpers for PhoneNumber
 // queries
 type PhoneNumberModel struct {
-	mapper    *PhoneNumberMapper
+	meta      *yago.Metadata
+	mapper    yago.Mapper
 	PersonID  yago.ScalarField
 	Name      yago.ScalarField
 	Number    yago.ScalarField
@@ -266,7 +294,12 @@ type PhoneNumberModel struct {
 func NewPhoneNumberModel(meta *yago.Metadata) PhoneNumberModel {
 	mapper := NewPhoneNumberMapper()
 	meta.AddMapper(mapper)
+	return newPhoneNumberModel(meta, mapper)
+}
+
+func newPhoneNumberModel(meta *yago.Metadata, mapper yago.Mapper) PhoneNumberModel {
 	return PhoneNumberModel{
+		meta:      meta,
 		mapper:    mapper,
 		PersonID:  yago.NewScalarField(mapper.Table().C(PhoneNumberPersonIDColumnName)),
 		Name:      yago.NewScalarField(mapper.Table().C(PhoneNumberNameColumnName)),
@@ -282,6 +315,27 @@ func (m PhoneNumberModel) GetMapper() yago.Mapper {
 	return m.mapper
 }
 
+// Using returns a PhoneNumberModel that uses a named mapper, see
+// yago.Metadata.AddNamedMapper. It panics if there is no such mapper, use
+// Lookup to get an error instead.
+func (m PhoneNumberModel) Using(name string) PhoneNumberModel {
+	model, err := m.Lookup(name)
+	if err != nil {
+		panic(fmt.Sprintf("PhoneNumber has no '%s' mapper", name))
+	}
+	return model
+}
+
+// Lookup returns a PhoneNumberModel that uses a named mapper, or
+// yago.ErrMapperNotFound
+func (m PhoneNumberModel) Lookup(name string) (PhoneNumberModel, error) {
+	mapper, err := m.meta.LookupNamedMapper(PhoneNumber{}, name)
+	if err != nil {
+		return PhoneNumberModel{}, err
+	}
+	return newPhoneNumberModel(m.meta, mapper), nil
+}
+
 // NewPhoneNumberMapper initialize a NewPhoneNumberMapper
 func NewPhoneNumberMapper() *PhoneNumberMapper {
 	m := &PhoneNumberMapper{}
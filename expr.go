@@ -0,0 +1,107 @@
+package yago
+
+import (
+	"strings"
+
+	"github.com/slicebit/qb"
+)
+
+// Expr is a SQL expression built from fields, values and functions. It can
+// be used in an update with Set, or as any qb.Clause.
+type Expr struct {
+	clause qb.Clause
+}
+
+// Accept compiles the expression
+func (e Expr) Accept(context *qb.CompilerContext) string {
+	return e.clause.Accept(context)
+}
+
+// asClause returns value if it is a clause, or binds it
+func asClause(value interface{}) qb.Clause {
+	if clause, ok := value.(qb.Clause); ok {
+		return clause
+	}
+	return qb.Bind(value)
+}
+
+// operatorClause is a '(<left> <operator> <right>)' clause
+type operatorClause struct {
+	left     qb.Clause
+	operator string
+	right    qb.Clause
+}
+
+// Accept compiles the clause
+func (c operatorClause) Accept(context *qb.CompilerContext) string {
+	return "(" + c.left.Accept(context) + " " + c.operator + " " + c.right.Accept(context) + ")"
+}
+
+func (e Expr) operator(operator string, value interface{}) Expr {
+	return Expr{operatorClause{e.clause, operator, asClause(value)}}
+}
+
+// Add returns a '+' expression
+func (e Expr) Add(value interface{}) Expr {
+	return e.operator("+", value)
+}
+
+// Sub returns a '-' expression
+func (e Expr) Sub(value interface{}) Expr {
+	return e.operator("-", value)
+}
+
+// Mul returns a '*' expression
+func (e Expr) Mul(value interface{}) Expr {
+	return e.operator("*", value)
+}
+
+// Div returns a '/' expression
+func (e Expr) Div(value interface{}) Expr {
+	return e.operator("/", value)
+}
+
+// funcClause is a SQL function call
+type funcClause struct {
+	name string
+	args []qb.Clause
+}
+
+// Accept compiles the clause
+func (c funcClause) Accept(context *qb.CompilerContext) string {
+	var args []string
+	for _, arg := range c.args {
+		args = append(args, arg.Accept(context))
+	}
+	return c.name + "(" + strings.Join(args, ", ") + ")"
+}
+
+// Func returns a SQL function call expression. The arguments can be
+// fields, expressions or values.
+func Func(name string, args ...interface{}) Expr {
+	clause := funcClause{name: name}
+	for _, arg := range args {
+		clause.args = append(clause.args, asClause(arg))
+	}
+	return Expr{clause}
+}
+
+// Add returns a '+' expression
+func (f ScalarField) Add(value interface{}) Expr {
+	return Expr{f}.Add(value)
+}
+
+// Sub returns a '-' expression
+func (f ScalarField) Sub(value interface{}) Expr {
+	return Expr{f}.Sub(value)
+}
+
+// Mul returns a '*' expression
+func (f ScalarField) Mul(value interface{}) Expr {
+	return Expr{f}.Mul(value)
+}
+
+// Div returns a '/' expression
+func (f ScalarField) Div(value interface{}) Expr {
+	return Expr{f}.Div(value)
+}
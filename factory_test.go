@@ -0,0 +1,65 @@
+package yago_test
+
+import (
+	"testing"
+
+	"github.com/m4rw3r/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFactory(t *testing.T) {
+	db, model, cleanup := initModel(t)
+	defer cleanup()
+
+	f := NewSimpleStructFactory()
+	s1 := f.Build()
+	s2 := f.Build()
+	assert.NotEqual(t, s1.Name, s2.Name)
+	assert.NotEqual(t, s1.Counter, s2.Counter)
+
+	s, err := f.Create(db)
+	assert.Nil(t, err)
+	assert.NotEqual(t, int64(0), s.ID)
+	_, err = f.Create(db)
+	assert.Nil(t, err)
+
+	// the options override the defaults
+	f = NewSimpleStructFactory(func(s *SimpleStruct) { s.Counter = 42 })
+	s, err = f.Create(db, func(s *SimpleStruct) { s.Name = "forty-two" })
+	assert.Nil(t, err)
+	assert.Equal(t, 42, s.Counter)
+
+	var loaded SimpleStruct
+	assert.Nil(t, db.Query(model.SimpleStruct).Get(&loaded, s.ID))
+	assert.Equal(t, "forty-two", loaded.Name)
+
+	var count int
+	assert.Nil(t, db.Query(model.SimpleStruct).Count(&count))
+	assert.Equal(t, 3, count)
+}
+
+func TestFactoryParents(t *testing.T) {
+	db, model, cleanup := initModel(t)
+	defer cleanup()
+
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+
+	f := NewAutoIncChildFactory()
+	child, err := f.Create(tx)
+	assert.Nil(t, err)
+	assert.NotEqual(t, uuid.UUID{}, child.Person)
+
+	var person PersonStruct
+	assert.Nil(t, tx.Query(model.PersonStruct).Get(&person, child.Person))
+
+	// a parent that is set is not created
+	child, err = f.Create(tx, func(c *AutoIncChild) { c.Person = person.ID })
+	assert.Nil(t, err)
+	assert.Equal(t, person.ID, child.Person)
+
+	var count int
+	assert.Nil(t, tx.Query(model.PersonStruct).Count(&count))
+	assert.Equal(t, 1, count)
+}
@@ -0,0 +1,424 @@
+package yago
+
+import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/slicebit/qb"
+)
+
+// FilterOperator is a dynamic filter operator. It is given as a suffix of
+// the filter keys, separated by FilterOperatorSeparator: "created_at__gte"
+type FilterOperator string
+
+// The dynamic filter operators
+const (
+	OpEq         FilterOperator = "eq"
+	OpNotEq      FilterOperator = "ne"
+	OpGt         FilterOperator = "gt"
+	OpGte        FilterOperator = "gte"
+	OpLt         FilterOperator = "lt"
+	OpLte        FilterOperator = "lte"
+	OpIn         FilterOperator = "in"
+	OpNotIn      FilterOperator = "notin"
+	OpLike       FilterOperator = "like"
+	OpILike      FilterOperator = "ilike"
+	OpStartsWith FilterOperator = "startswith"
+	OpEndsWith   FilterOperator = "endswith"
+	OpContains   FilterOperator = "contains"
+	OpIsNull     FilterOperator = "isnull"
+)
+
+// FilterOperatorSeparator separates the field name and the operator in
+// the dynamic filter keys
+const FilterOperatorSeparator = "__"
+
+// SortParam is the url parameter holding the sort fields in FilterByURL.
+// The fields are separated by commas, and prefixed with '-' for a
+// descending order: "sort=name,-created_at"
+const SortParam = "sort"
+
+// AllowedFilters is an allowlist of the fields, and of their operators,
+// that can be used in dynamic filters. An empty operator list allows all
+// the operators. A nil AllowedFilters allows all the model fields.
+type AllowedFilters map[string][]FilterOperator
+
+// filterableField is implemented by ScalarField and MarshaledScalarField
+type filterableField interface {
+	qb.Clause
+	Eq(value interface{}) qb.Clause
+	NotEq(value interface{}) qb.Clause
+	Gt(value interface{}) qb.Clause
+	Gte(value interface{}) qb.Clause
+	Lt(value interface{}) qb.Clause
+	Lte(value interface{}) qb.Clause
+	In(values ...interface{}) qb.Clause
+	NotIn(values ...interface{}) qb.Clause
+	Like(pattern string) qb.Clause
+	IsNull() qb.Clause
+	IsNotNull() qb.Clause
+	Asc() OrderTerm
+	Desc() OrderTerm
+	// scalar returns the unmarshaled field, for the LIKE operators that
+	// take the value as text
+	scalar() ScalarField
+}
+
+// modelField is a model field resolved from its name
+type modelField struct {
+	name   string
+	field  filterableField
+	goType reflect.Type
+}
+
+var (
+	scalarFieldType          = reflect.TypeOf(ScalarField{})
+	marshaledScalarFieldType = reflect.TypeOf(MarshaledScalarField{})
+)
+
+// modelFields returns the fields of a model, by Go field name and by column
+// name
+func modelFields(model MapperProvider, mapper Mapper) map[string]modelField {
+	fields := make(map[string]modelField)
+	value := reflect.Indirect(reflect.ValueOf(model))
+	if value.Kind() != reflect.Struct {
+		return fields
+	}
+	structType := mapper.StructType()
+	for i := 0; i < value.NumField(); i++ {
+		f := value.Type().Field(i)
+		if f.Type != scalarFieldType && f.Type != marshaledScalarFieldType {
+			continue
+		}
+		structField, ok := structType.FieldByName(f.Name)
+		if !ok {
+			continue
+		}
+		field := value.Field(i).Interface().(filterableField)
+		mf := modelField{name: f.Name, field: field, goType: structField.Type}
+		fields[f.Name] = mf
+		if f.Type == scalarFieldType {
+			fields[field.(ScalarField).Column.Name] = mf
+		} else {
+			fields[field.(MarshaledScalarField).Column.Name] = mf
+		}
+	}
+	return fields
+}
+
+// isAllowed returns true if the operator is allowed on the field
+func (a AllowedFilters) isAllowed(field string, op FilterOperator) bool {
+	if a == nil {
+		return true
+	}
+	ops, ok := a[field]
+	if !ok {
+		return false
+	}
+	if len(ops) == 0 {
+		return true
+	}
+	for _, allowed := range ops {
+		if allowed == op {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve returns the model field matching a name, if allowed
+func (a AllowedFilters) resolve(
+	fields map[string]modelField, name string, op FilterOperator,
+) (modelField, error) {
+	field, ok := fields[name]
+	if !ok {
+		return field, fmt.Errorf("yago Query.FilterBy(): Unknown field '%s'", name)
+	}
+	if !a.isAllowed(field.name, op) {
+		return field, fmt.Errorf(
+			"yago Query.FilterBy(): Operator '%s' is not allowed on field '%s'", op, name)
+	}
+	return field, nil
+}
+
+// convertFilterValue converts a string to a Go type
+func convertFilterValue(t reflect.Type, value string) (interface{}, error) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == reflect.TypeOf(time.Time{}) {
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02"} {
+			if tm, err := time.Parse(layout, value); err == nil {
+				return tm, nil
+			}
+		}
+		return nil, fmt.Errorf("Invalid time '%s'", value)
+	}
+	ptr := reflect.New(t)
+	if u, ok := ptr.Interface().(encoding.TextUnmarshaler); ok {
+		if err := u.UnmarshalText([]byte(value)); err != nil {
+			return nil, err
+		}
+		return ptr.Elem().Interface(), nil
+	}
+	v := ptr.Elem()
+	switch t.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, t.Bits())
+		if err != nil {
+			return nil, err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(value, 10, t.Bits())
+		if err != nil {
+			return nil, err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, t.Bits())
+		if err != nil {
+			return nil, err
+		}
+		v.SetFloat(f)
+	default:
+		return nil, fmt.Errorf("Cannot convert a string to %s", t)
+	}
+	return v.Interface(), nil
+}
+
+// convert converts a filter value to the field type, if it is a string
+func (f modelField) convert(value interface{}) (interface{}, error) {
+	s, ok := value.(string)
+	if !ok {
+		return value, nil
+	}
+	converted, err := convertFilterValue(f.goType, s)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"yago Query.FilterBy(): Invalid value for field '%s': %s", f.name, err)
+	}
+	return converted, nil
+}
+
+// convertList converts a IN filter value, which can be a slice or a comma
+// separated string
+func (f modelField) convertList(value interface{}) ([]interface{}, error) {
+	var values []interface{}
+	if s, ok := value.(string); ok {
+		for _, item := range strings.Split(s, ",") {
+			values = append(values, item)
+		}
+	} else if v := reflect.ValueOf(value); v.Kind() == reflect.Slice {
+		for i := 0; i < v.Len(); i++ {
+			values = append(values, v.Index(i).Interface())
+		}
+	} else {
+		values = append(values, value)
+	}
+	for i := range values {
+		converted, err := f.convert(values[i])
+		if err != nil {
+			return nil, err
+		}
+		values[i] = converted
+	}
+	return values, nil
+}
+
+// stringValue returns the value as a string, for the LIKE operators
+func stringValue(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	return fmt.Sprint(value)
+}
+
+// filterClause builds the clause of a single filter
+func (f modelField) filterClause(op FilterOperator, value interface{}) (qb.Clause, error) {
+	switch op {
+	case OpIn, OpNotIn:
+		values, err := f.convertList(value)
+		if err != nil {
+			return nil, err
+		}
+		if op == OpIn {
+			return f.field.In(values...), nil
+		}
+		return f.field.NotIn(values...), nil
+	case OpLike:
+		return f.field.Like(stringValue(value)), nil
+	case OpILike:
+		return f.field.scalar().ILike(stringValue(value)), nil
+	case OpStartsWith:
+		return f.field.scalar().StartsWith(stringValue(value)), nil
+	case OpEndsWith:
+		return f.field.scalar().EndsWith(stringValue(value)), nil
+	case OpContains:
+		return f.field.scalar().Contains(stringValue(value)), nil
+	case OpIsNull:
+		isNull, ok := value.(bool)
+		if !ok {
+			b, err := strconv.ParseBool(stringValue(value))
+			if err != nil {
+				return nil, fmt.Errorf(
+					"yago Query.FilterBy(): Invalid isnull value for field '%s': %s", f.name, err)
+			}
+			isNull = b
+		}
+		if isNull {
+			return f.field.IsNull(), nil
+		}
+		return f.field.IsNotNull(), nil
+	}
+
+	converted, err := f.convert(value)
+	if err != nil {
+		return nil, err
+	}
+	switch op {
+	case OpEq:
+		return f.field.Eq(converted), nil
+	case OpNotEq:
+		return f.field.NotEq(converted), nil
+	case OpGt:
+		return f.field.Gt(converted), nil
+	case OpGte:
+		return f.field.Gte(converted), nil
+	case OpLt:
+		return f.field.Lt(converted), nil
+	case OpLte:
+		return f.field.Lte(converted), nil
+	}
+	return nil, fmt.Errorf("yago Query.FilterBy(): Unknown operator '%s'", op)
+}
+
+// splitFilterKey splits a filter key in a field name and an operator
+func splitFilterKey(key string) (string, FilterOperator) {
+	if i := strings.LastIndex(key, FilterOperatorSeparator); i != -1 {
+		return key[:i], FilterOperator(key[i+len(FilterOperatorSeparator):])
+	}
+	return key, OpEq
+}
+
+// FilterBy filters the query from a map of filters, which keys are field
+// names (Go or column names) optionally followed by an operator:
+// "Name", "created_at__gte". String values are converted to the field Go
+// type.
+// The query must have been created from a generated Model, with
+// IDB.Query() or IDB.RawQuery().
+func (q Query) FilterBy(filters map[string]interface{}) Query {
+	return q.FilterByAllowed(nil, filters)
+}
+
+// FilterByAllowed filters the query like FilterBy, rejecting the fields and
+// operators that are not in allowed
+func (q Query) FilterByAllowed(allowed AllowedFilters, filters map[string]interface{}) Query {
+	if q.model == nil {
+		return q.setErr(fmt.Errorf("yago Query.FilterBy(): The query has no model"))
+	}
+	fields := modelFields(q.model, q.mapper)
+
+	keys := make([]string, 0, len(filters))
+	for key := range filters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var clauses []qb.Clause
+	for _, key := range keys {
+		name, op := splitFilterKey(key)
+		field, err := allowed.resolve(fields, name, op)
+		if err != nil {
+			return q.setErr(err)
+		}
+		clause, err := field.filterClause(op, filters[key])
+		if err != nil {
+			return q.setErr(err)
+		}
+		clauses = append(clauses, clause)
+	}
+	if len(clauses) == 0 {
+		return q
+	}
+	return q.Filter(clauses...)
+}
+
+// SortBy orders the query by field names (Go or column names), prefixed
+// with '-' for a descending order. The fields must be in allowed, unless
+// it is nil. The empty names are ignored.
+func (q Query) SortBy(allowed AllowedFilters, names ...string) Query {
+	if q.model == nil {
+		return q.setErr(fmt.Errorf("yago Query.SortBy(): The query has no model"))
+	}
+	fields := modelFields(q.model, q.mapper)
+	var terms []qb.Clause
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		desc := strings.HasPrefix(name, "-")
+		name = strings.TrimPrefix(name, "-")
+		field, ok := fields[name]
+		if !ok {
+			return q.setErr(fmt.Errorf("yago Query.SortBy(): Unknown field '%s'", name))
+		}
+		if _, ok := allowed[field.name]; allowed != nil && !ok {
+			return q.setErr(fmt.Errorf(
+				"yago Query.SortBy(): Field '%s' is not allowed", name))
+		}
+		if desc {
+			terms = append(terms, field.field.Desc())
+		} else {
+			terms = append(terms, field.field.Asc())
+		}
+	}
+	if len(terms) == 0 {
+		return q
+	}
+	return q.OrderBy(terms...)
+}
+
+// FilterByURL filters and sorts the query from url parameters, like
+// "?name=Toto&created_at__gte=2020-01-01&sort=-created_at".
+// The multiple values of a 'in' or 'notin' filter are combined, other
+// filters use the first value.
+func (q Query) FilterByURL(allowed AllowedFilters, values url.Values) Query {
+	filters := make(map[string]interface{})
+	var sortFields []string
+	for key, v := range values {
+		if len(v) == 0 {
+			continue
+		}
+		if key == SortParam {
+			for _, s := range v {
+				for _, name := range strings.Split(s, ",") {
+					if name != "" {
+						sortFields = append(sortFields, name)
+					}
+				}
+			}
+			continue
+		}
+		if _, op := splitFilterKey(key); op == OpIn || op == OpNotIn {
+			filters[key] = strings.Join(v, ",")
+		} else {
+			filters[key] = v[0]
+		}
+	}
+	return q.FilterByAllowed(allowed, filters).SortBy(allowed, sortFields...)
+}
@@ -0,0 +1,120 @@
+package yago_test
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/orus-io/yago"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterBy(t *testing.T) {
+	db, model, cleanup := initModel(t)
+	defer cleanup()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	for i, p := range []PersonStruct{
+		{FirstName: "John", LastName: "Doe", Gender: Male, Active: true},
+		{FirstName: "Jane", LastName: "Doe", Gender: Female},
+		{FirstName: "Malcom", LastName: "X", Gender: Male, Active: true},
+	} {
+		p.CreatedAt = now.Add(time.Duration(i) * time.Hour)
+		assert.Nil(t, db.Insert(&p))
+	}
+
+	for _, tt := range []struct {
+		filters map[string]interface{}
+		count   int
+	}{
+		{map[string]interface{}{"FirstName": "John"}, 1},
+		{map[string]interface{}{"last_name": "Doe"}, 2},
+		{map[string]interface{}{"LastName__ne": "Doe"}, 1},
+		{map[string]interface{}{"Active": "true"}, 2},
+		{map[string]interface{}{"Active": false}, 1},
+		{map[string]interface{}{"Gender": "female"}, 1},
+		{map[string]interface{}{"Gender__in": "male,female"}, 3},
+		{map[string]interface{}{"first_name__in": []string{"John", "Jane"}}, 2},
+		{map[string]interface{}{"FirstName__startswith": "J", "Gender": Male}, 1},
+		{map[string]interface{}{"created_at__gte": now.Add(time.Hour).Format(time.RFC3339)}, 2},
+		{map[string]interface{}{"LastName__isnull": "false"}, 3},
+		{map[string]interface{}{}, 3},
+	} {
+		var count int
+		q := db.Query(model.PersonStruct).FilterBy(tt.filters)
+		assert.Nil(t, q.Err(), "%v", tt.filters)
+		assert.Nil(t, q.Count(&count))
+		assert.Equal(t, tt.count, count, "%v", tt.filters)
+	}
+}
+
+func TestFilterByErrors(t *testing.T) {
+	db, model, cleanup := initModel(t)
+	defer cleanup()
+
+	allowed := yago.AllowedFilters{
+		"FirstName": {yago.OpEq, yago.OpStartsWith},
+		"Active":    nil,
+	}
+	for _, tt := range []struct {
+		filters map[string]interface{}
+		err     string
+	}{
+		{map[string]interface{}{"Unknown": "x"},
+			"yago Query.FilterBy(): Unknown field 'Unknown'"},
+		{map[string]interface{}{"LastName": "Doe"},
+			"yago Query.FilterBy(): Operator 'eq' is not allowed on field 'LastName'"},
+		{map[string]interface{}{"first_name__contains": "o"},
+			"yago Query.FilterBy(): Operator 'contains' is not allowed on field 'first_name'"},
+		{map[string]interface{}{"Active": "maybe"},
+			`yago Query.FilterBy(): Invalid value for field 'Active': strconv.ParseBool: parsing "maybe": invalid syntax`},
+		{map[string]interface{}{"Active__between": "true"},
+			"yago Query.FilterBy(): Unknown operator 'between'"},
+	} {
+		q := db.Query(model.PersonStruct).FilterByAllowed(allowed, tt.filters)
+		if assert.NotNil(t, q.Err(), "%v", tt.filters) {
+			assert.Equal(t, tt.err, q.Err().Error())
+		}
+	}
+
+	q := db.Query(model.PersonStruct).FilterBy(map[string]interface{}{"ID": "not-a-uuid"})
+	assert.NotNil(t, q.Err())
+}
+
+func TestFilterByURL(t *testing.T) {
+	db, model, cleanup := initModel(t)
+	defer cleanup()
+
+	for _, name := range []string{"Jane", "John", "Malcom", "Bob"} {
+		p := PersonStruct{FirstName: name, Active: name != "Bob"}
+		assert.Nil(t, db.Insert(&p))
+	}
+
+	allowed := yago.AllowedFilters{
+		"FirstName": nil,
+		"Active":    {yago.OpEq},
+	}
+	values, err := url.ParseQuery("active=true&first_name__in=Jane&first_name__in=John,Bob&sort=-first_name")
+	assert.Nil(t, err)
+
+	var persons []PersonStruct
+	assert.Nil(t, db.Query(model.PersonStruct).FilterByURL(allowed, values).All(&persons))
+	if assert.Len(t, persons, 2) {
+		assert.Equal(t, "John", persons[0].FirstName)
+		assert.Equal(t, "Jane", persons[1].FirstName)
+	}
+
+	values, err = url.ParseQuery("sort=last_name")
+	assert.Nil(t, err)
+	q := db.Query(model.PersonStruct).FilterByURL(allowed, values)
+	if assert.NotNil(t, q.Err()) {
+		assert.Equal(t, "yago Query.SortBy(): Field 'last_name' is not allowed", q.Err().Error())
+	}
+
+	// the empty sort items are ignored
+	for _, query := range []string{"sort=", "sort=first_name,", "sort=,-first_name&sort="} {
+		values, err = url.ParseQuery(query)
+		assert.Nil(t, err)
+		assert.Nil(t, db.Query(model.PersonStruct).FilterByURL(allowed, values).Err(), query)
+	}
+}
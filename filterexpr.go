@@ -0,0 +1,462 @@
+package yago
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/slicebit/qb"
+)
+
+// FilterSyntaxError is a error in a filter expression
+type FilterSyntaxError struct {
+	// Pos is the position of the error in the expression, starting at 1
+	Pos int
+	Msg string
+}
+
+// Error returns the error message
+func (e *FilterSyntaxError) Error() string {
+	return fmt.Sprintf("yago filter expression: position %d: %s", e.Pos, e.Msg)
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokTime
+	tokOperator
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+// token is a lexical token of a filter expression
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// keyword returns the lowercased identifier, or "" if the token is not an
+// identifier
+func (t token) keyword() string {
+	if t.kind != tokIdent {
+		return ""
+	}
+	return strings.ToLower(t.text)
+}
+
+// describe returns a description of the token for the error messages
+func (t token) describe() string {
+	if t.kind == tokEOF {
+		return "end of expression"
+	}
+	return fmt.Sprintf("'%s'", t.text)
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// isTimeStart returns true if s starts with a 'YYYY-' date
+func isTimeStart(s []rune) bool {
+	if len(s) < 5 || s[4] != '-' {
+		return false
+	}
+	for _, r := range s[:4] {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// lexFilter splits a filter expression in tokens
+func lexFilter(expr string) ([]token, error) {
+	var tokens []token
+	s := []rune(expr)
+	i := 0
+	for i < len(s) {
+		r := s[i]
+		start := i
+		switch {
+		case unicode.IsSpace(r):
+			i++
+			continue
+		case r == '(':
+			tokens = append(tokens, token{tokLParen, "(", start + 1})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{tokRParen, ")", start + 1})
+			i++
+		case r == ',':
+			tokens = append(tokens, token{tokComma, ",", start + 1})
+			i++
+		case r == '"' || r == '\'':
+			var value []rune
+			i++
+			for ; i < len(s) && s[i] != r; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				value = append(value, s[i])
+			}
+			if i == len(s) {
+				return nil, &FilterSyntaxError{start + 1, "Unterminated string"}
+			}
+			i++
+			tokens = append(tokens, token{tokString, string(value), start + 1})
+		case isTimeStart(s[i:]):
+			for i < len(s) && (unicode.IsDigit(s[i]) || strings.ContainsRune("-:.TZ+", s[i])) {
+				i++
+			}
+			tokens = append(tokens, token{tokTime, string(s[start:i]), start + 1})
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(s) && unicode.IsDigit(s[i+1])):
+			i++
+			for i < len(s) && (unicode.IsDigit(s[i]) || s[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{tokNumber, string(s[start:i]), start + 1})
+		case unicode.IsLetter(r) || r == '_':
+			for i < len(s) && isIdentRune(s[i]) {
+				i++
+			}
+			tokens = append(tokens, token{tokIdent, string(s[start:i]), start + 1})
+		case strings.ContainsRune("=!<>~", r):
+			i++
+			if i < len(s) && strings.ContainsRune("=>*", s[i]) {
+				i++
+			}
+			op := string(s[start:i])
+			if _, ok := filterExprOperators[op]; !ok {
+				return nil, &FilterSyntaxError{start + 1, fmt.Sprintf("Unknown operator '%s'", op)}
+			}
+			tokens = append(tokens, token{tokOperator, op, start + 1})
+		default:
+			return nil, &FilterSyntaxError{start + 1, fmt.Sprintf("Unexpected character '%c'", r)}
+		}
+	}
+	return append(tokens, token{tokEOF, "", len(s) + 1}), nil
+}
+
+// filterExprOperators are the comparison operators of the filter expressions
+var filterExprOperators = map[string]FilterOperator{
+	"=":  OpEq,
+	"==": OpEq,
+	"!=": OpNotEq,
+	"<>": OpNotEq,
+	"<":  OpLt,
+	"<=": OpLte,
+	">":  OpGt,
+	">=": OpGte,
+	"~":  OpLike,
+	"~*": OpILike,
+}
+
+// maxFilterDepth is the maximum nesting depth of the 'not' and parentheses
+// in a filter expression
+const maxFilterDepth = 100
+
+// filterParser is a recursive descent parser of filter expressions
+type filterParser struct {
+	tokens  []token
+	pos     int
+	depth   int
+	fields  map[string]modelField
+	allowed AllowedFilters
+}
+
+func (p *filterParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *filterParser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *filterParser) errorf(t token, format string, args ...interface{}) error {
+	return &FilterSyntaxError{t.pos, fmt.Sprintf(format, args...)}
+}
+
+func (p *filterParser) expect(kind tokenKind, what string) (token, error) {
+	t := p.next()
+	if t.kind != kind {
+		return t, p.errorf(t, "Expected %s, got %s", what, t.describe())
+	}
+	return t, nil
+}
+
+// parseOr parses 'and-expr ("or" and-expr)*'
+func (p *filterParser) parseOr() (qb.Clause, error) {
+	clauses, err := p.parseList("or", p.parseAnd)
+	if err != nil || len(clauses) == 1 {
+		return clauses[0], err
+	}
+	return qb.Or(clauses...), nil
+}
+
+// parseAnd parses 'not-expr ("and" not-expr)*'
+func (p *filterParser) parseAnd() (qb.Clause, error) {
+	clauses, err := p.parseList("and", p.parseNot)
+	if err != nil || len(clauses) == 1 {
+		return clauses[0], err
+	}
+	return qb.And(clauses...), nil
+}
+
+// parseList parses a list of operands separated by a keyword
+func (p *filterParser) parseList(
+	keyword string, parseOperand func() (qb.Clause, error),
+) ([]qb.Clause, error) {
+	var clauses []qb.Clause
+	for {
+		clause, err := parseOperand()
+		if err != nil {
+			return []qb.Clause{nil}, err
+		}
+		clauses = append(clauses, clause)
+		if p.peek().keyword() != keyword {
+			return clauses, nil
+		}
+		p.next()
+	}
+}
+
+// parseNot parses '"not" not-expr | "(" or-expr ")" | comparison'
+func (p *filterParser) parseNot() (qb.Clause, error) {
+	t := p.peek()
+	if t.keyword() == "not" || t.kind == tokLParen {
+		if p.depth == maxFilterDepth {
+			return nil, p.errorf(t, "Expression nested too deeply")
+		}
+		p.depth++
+		defer func() { p.depth-- }()
+	}
+	switch {
+	case t.keyword() == "not":
+		p.next()
+		clause, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return Not(clause), nil
+	case t.kind == tokLParen:
+		p.next()
+		clause, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokRParen, "')'"); err != nil {
+			return nil, err
+		}
+		return clause, nil
+	default:
+		return p.parseComparison()
+	}
+}
+
+// parseComparison parses 'field operator literal', 'field is [not] null'
+// and 'field [not] in (literal, ...)'
+func (p *filterParser) parseComparison() (qb.Clause, error) {
+	fieldToken, err := p.expect(tokIdent, "a field name")
+	if err != nil {
+		return nil, err
+	}
+	switch fieldToken.keyword() {
+	case "and", "or", "not", "is", "in", "null", "true", "false":
+		return nil, p.errorf(fieldToken, "Expected a field name, got %s", fieldToken.describe())
+	}
+	field, ok := p.fields[fieldToken.text]
+	if !ok {
+		return nil, p.errorf(fieldToken, "Unknown field '%s'", fieldToken.text)
+	}
+
+	var (
+		op    FilterOperator
+		value interface{}
+	)
+	opToken := p.next()
+	switch {
+	case opToken.kind == tokOperator:
+		op = filterExprOperators[opToken.text]
+		lit := p.next()
+		if op == OpLike || op == OpILike {
+			if lit.kind != tokString {
+				return nil, p.errorf(lit, "Expected a string, got %s", lit.describe())
+			}
+			value = lit.text
+		} else if value, err = p.literal(field, lit); err != nil {
+			return nil, err
+		}
+	case opToken.keyword() == "is":
+		op = OpIsNull
+		value = true
+		if p.peek().keyword() == "not" {
+			p.next()
+			value = false
+		}
+		if t := p.next(); t.keyword() != "null" {
+			return nil, p.errorf(t, "Expected 'null', got %s", t.describe())
+		}
+	case opToken.keyword() == "in" || opToken.keyword() == "not":
+		op = OpIn
+		if opToken.keyword() == "not" {
+			op = OpNotIn
+			if t := p.next(); t.keyword() != "in" {
+				return nil, p.errorf(t, "Expected 'in', got %s", t.describe())
+			}
+		}
+		if value, err = p.literalList(field); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, p.errorf(opToken, "Expected an operator, got %s", opToken.describe())
+	}
+
+	if !p.allowed.isAllowed(field.name, op) {
+		return nil, p.errorf(opToken,
+			"Operator '%s' is not allowed on field '%s'", opToken.text, fieldToken.text)
+	}
+	clause, err := field.filterClause(op, value)
+	if err != nil {
+		return nil, p.errorf(opToken, "%s", err)
+	}
+	return clause, nil
+}
+
+// literalList parses '(literal, ...)'
+func (p *filterParser) literalList(field modelField) ([]interface{}, error) {
+	if _, err := p.expect(tokLParen, "'('"); err != nil {
+		return nil, err
+	}
+	var values []interface{}
+	for {
+		value, err := p.literal(field, p.next())
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+		if t := p.next(); t.kind == tokRParen {
+			return values, nil
+		} else if t.kind != tokComma {
+			return nil, p.errorf(t, "Expected ',' or ')', got %s", t.describe())
+		}
+	}
+}
+
+// literal converts a literal to the field Go type
+func (p *filterParser) literal(field modelField, lit token) (interface{}, error) {
+	t := field.goType
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	mismatch := func(what string) error {
+		return p.errorf(lit, "Cannot compare field '%s' to a %s", field.name, what)
+	}
+	switch lit.kind {
+	case tokString:
+		value, err := convertFilterValue(t, lit.text)
+		if err != nil {
+			return nil, p.errorf(lit, "Invalid value for field '%s': %s", field.name, err)
+		}
+		return value, nil
+	case tokNumber:
+		switch t.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			value, err := convertFilterValue(t, lit.text)
+			if err != nil {
+				return nil, p.errorf(lit, "Invalid value for field '%s': %s", field.name, err)
+			}
+			return value, nil
+		}
+		return nil, mismatch("number")
+	case tokTime:
+		if t != reflect.TypeOf(time.Time{}) {
+			return nil, mismatch("date")
+		}
+		value, err := convertFilterValue(t, lit.text)
+		if err != nil {
+			return nil, p.errorf(lit, "%s", err)
+		}
+		return value, nil
+	case tokIdent:
+		switch lit.keyword() {
+		case "true", "false":
+			if t.Kind() != reflect.Bool {
+				return nil, mismatch("boolean")
+			}
+			b, _ := strconv.ParseBool(lit.keyword())
+			return b, nil
+		case "null":
+			return nil, p.errorf(lit, "Use 'is null' to compare to null")
+		}
+	}
+	return nil, p.errorf(lit, "Expected a value, got %s", lit.describe())
+}
+
+// ParseFilterExpr compiles a filter expression to a clause on the model
+// fields, which are designated by their Go or column names:
+//
+//	name ~ "To%" and (created_at > 2020-01-01 or email is null)
+//
+// The comparison operators are =, !=, <>, <, <=, >, >=, ~ (LIKE), ~*
+// (ILIKE), 'is [not] null' and '[not] in (...)'. They can be combined
+// with 'and', 'or', 'not' and parentheses.
+// The literals are strings, numbers, true/false and dates/times. The fields
+// and operators must be in allowed, unless it is nil.
+func ParseFilterExpr(model MapperProvider, allowed AllowedFilters, expr string) (qb.Clause, error) {
+	if v := reflect.ValueOf(model); !v.IsValid() || v.Kind() == reflect.Ptr && v.IsNil() {
+		return nil, fmt.Errorf("yago ParseFilterExpr: No model")
+	}
+	tokens, err := lexFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := filterParser{
+		tokens:  tokens,
+		fields:  modelFields(model, model.GetMapper()),
+		allowed: allowed,
+	}
+	if allowed != nil {
+		for name, field := range p.fields {
+			if _, ok := allowed[field.name]; !ok {
+				delete(p.fields, name)
+			}
+		}
+	}
+	clause, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.errorf(t, "Unexpected %s", t.describe())
+	}
+	return clause, nil
+}
+
+// FilterExpr filters the query with a filter expression, see
+// ParseFilterExpr.
+// The query must have been created from a generated Model.
+func (q Query) FilterExpr(allowed AllowedFilters, expr string) Query {
+	if q.model == nil {
+		return q.setErr(fmt.Errorf("yago Query.FilterExpr(): The query has no model"))
+	}
+	clause, err := ParseFilterExpr(q.model, allowed, expr)
+	if err != nil {
+		return q.setErr(err)
+	}
+	return q.Filter(clause)
+}
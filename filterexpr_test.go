@@ -0,0 +1,106 @@
+package yago_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/orus-io/yago"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterExpr(t *testing.T) {
+	db, model, cleanup := initModel(t)
+	defer cleanup()
+
+	date := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, p := range []PersonStruct{
+		{FirstName: "Toto", LastName: "Doe", Gender: Male, Active: true},
+		{FirstName: "Tata", LastName: "Doe", Gender: Female},
+		{FirstName: "John", Gender: Male, Active: true},
+	} {
+		p.CreatedAt = date.AddDate(0, 0, i-1)
+		assert.Nil(t, db.Insert(&p))
+	}
+	_, err := db.Engine.DB().Exec(
+		"UPDATE person_struct SET last_name = NULL WHERE first_name = 'John'")
+	assert.Nil(t, err)
+
+	for _, tt := range []struct {
+		expr  string
+		count int
+	}{
+		{`first_name = "Toto"`, 1},
+		{`FirstName != 'Toto'`, 2},
+		{`first_name ~ "T%"`, 2},
+		{`first_name ~* "t%"`, 2},
+		{`active = true`, 2},
+		{`gender = "female"`, 1},
+		{`gender in ("male", "female")`, 3},
+		{`gender not in ("male")`, 1},
+		{`last_name is null`, 1},
+		{`last_name is not null`, 2},
+		{`created_at > 2020-01-01`, 1},
+		{`created_at >= 2020-01-01T00:00:00Z`, 2},
+		{`first_name ~ "To%" and (created_at > 2020-01-01 or last_name is null)`, 0},
+		{`first_name ~ "T%" and created_at < 2020-01-01 or last_name is null`, 2},
+		{`not (active = true) or first_name = "John"`, 2},
+		{`NOT active = true AND first_name = "Tata"`, 1},
+	} {
+		var count int
+		q := db.Query(model.PersonStruct).FilterExpr(nil, tt.expr)
+		assert.Nil(t, q.Err(), tt.expr)
+		assert.Nil(t, q.Count(&count))
+		assert.Equal(t, tt.count, count, tt.expr)
+	}
+}
+
+func TestFilterExprErrors(t *testing.T) {
+	db, model, cleanup := initModel(t)
+	defer cleanup()
+
+	allowed := yago.AllowedFilters{
+		"FirstName": nil,
+		"Active":    {yago.OpEq},
+		"CreatedAt": nil,
+	}
+	for _, tt := range []struct {
+		expr string
+		pos  int
+		msg  string
+	}{
+		{`first_name = "Toto`, 14, "Unterminated string"},
+		{`first_name = "Toto" and`, 24, "Expected a field name, got end of expression"},
+		{`(first_name = "Toto"`, 21, "Expected ')', got end of expression"},
+		{`first_name = "Toto")`, 20, "Unexpected ')'"},
+		{`first_name "Toto"`, 12, "Expected an operator, got 'Toto'"},
+		{`first_name ^ "Toto"`, 12, "Unexpected character '^'"},
+		{`last_name = "Doe"`, 1, "Unknown field 'last_name'"},
+		{`active != true`, 8, "Operator '!=' is not allowed on field 'active'"},
+		{`active = 1`, 10, "Cannot compare field 'Active' to a number"},
+		{`created_at > "yesterday"`, 14, "Invalid value for field 'CreatedAt': Invalid time 'yesterday'"},
+		{`first_name = null`, 14, "Use 'is null' to compare to null"},
+		{`first_name ~ 12`, 14, "Expected a string, got '12'"},
+		{`first_name in ("a" "b")`, 20, "Expected ',' or ')', got 'b'"},
+		{strings.Repeat("(", 101) + `first_name = "Toto"` + strings.Repeat(")", 101), 101, "Expression nested too deeply"},
+		{strings.Repeat("not ", 101) + `first_name = "Toto"`, 401, "Expression nested too deeply"},
+	} {
+		_, err := yago.ParseFilterExpr(model.PersonStruct, allowed, tt.expr)
+		if assert.IsType(t, &yago.FilterSyntaxError{}, err, tt.expr) {
+			assert.Equal(t, tt.pos, err.(*yago.FilterSyntaxError).Pos, tt.expr)
+			assert.Equal(t, tt.msg, err.(*yago.FilterSyntaxError).Msg, tt.expr)
+		}
+	}
+
+	q := db.Query(model.PersonStruct).FilterExpr(allowed, "active = ")
+	if assert.NotNil(t, q.Err()) {
+		assert.Equal(t,
+			"yago filter expression: position 10: Expected a value, got end of expression",
+			q.Err().Error())
+	}
+
+	_, err := yago.ParseFilterExpr(nil, allowed, "active = true")
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "yago ParseFilterExpr: No model", err.Error())
+	}
+}
@@ -16,6 +16,7 @@ type FixtureModel struct {
 
 	PersonStruct PersonStructModel
 	SimpleStruct SimpleStructModel
+	AutoIncChild AutoIncChildModel
 }
 
 func NewFixtureModel(meta *yago.Metadata) FixtureModel {
@@ -23,13 +24,15 @@ func NewFixtureModel(meta *yago.Metadata) FixtureModel {
 		meta:         meta,
 		PersonStruct: NewPersonStructModel(meta),
 		SimpleStruct: NewSimpleStructModel(meta),
+		AutoIncChild: NewAutoIncChildModel(meta),
 	}
 }
 
-//yago:
+//yago:factory
 type SimpleStruct struct {
-	ID   int64  `yago:"primary_key,auto_increment"`
-	Name string `yago:"unique_index"`
+	ID      int64  `yago:"primary_key,auto_increment"`
+	Name    string `yago:"unique_index"`
+	Counter int    `yago:"notnull"`
 }
 
 //yago:notable,autoattrs
@@ -75,7 +78,7 @@ func (g *PersonGender) UnmarshalText(text []byte) error {
 	return nil
 }
 
-//yago:autoattrs
+//yago:autoattrs,factory
 type PersonStruct struct {
 	BaseStruct
 
@@ -91,7 +94,7 @@ type AutoIncBase struct {
 	ID int64 `yago:"primary_key,auto_increment"`
 }
 
-//yago:autoattrs
+//yago:autoattrs,factory
 type AutoIncChild struct {
 	AutoIncBase
 
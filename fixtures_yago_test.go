@@ -6,6 +6,7 @@ import (
 	"database/sql"
 	"fmt"
 	"reflect"
+	"time"
 
 	"github.com/slicebit/qb"
 
@@ -22,6 +23,10 @@ const (
 	SimpleStructName = "Name"
 	// SimpleStructNameColumnName is the Name field associated column name
 	SimpleStructNameColumnName = "name"
+	// SimpleStructCounter is the Counter field name
+	SimpleStructCounter = "Counter"
+	// SimpleStructCounterColumnName is the Counter field associated column name
+	SimpleStructCounterColumnName = "counter"
 )
 
 const (
@@ -33,6 +38,7 @@ var simpleStructTable = qb.Table(
 	SimpleStructTableName,
 	qb.Column(SimpleStructIDColumnName, qb.BigInt()).PrimaryKey().AutoIncrement().NotNull(),
 	qb.Column(SimpleStructNameColumnName, qb.Varchar()).NotNull(),
+	qb.Column(SimpleStructCounterColumnName, qb.Int()).NotNull(),
 	qb.UniqueKey(
 		SimpleStructNameColumnName,
 	),
@@ -50,19 +56,27 @@ func (SimpleStruct) StructType() reflect.Type {
 // SimpleStructModel provides direct access to helpers for SimpleStruct
 // queries
 type SimpleStructModel struct {
-	mapper *SimpleStructMapper
-	ID     yago.ScalarField
-	Name   yago.ScalarField
+	meta    *yago.Metadata
+	mapper  yago.Mapper
+	ID      yago.ScalarField
+	Name    yago.ScalarField
+	Counter yago.ScalarField
 }
 
 // NewSimpleStructModel returns a new SimpleStructModel
 func NewSimpleStructModel(meta *yago.Metadata) SimpleStructModel {
 	mapper := NewSimpleStructMapper()
 	meta.AddMapper(mapper)
+	return newSimpleStructModel(meta, mapper)
+}
+
+func newSimpleStructModel(meta *yago.Metadata, mapper yago.Mapper) SimpleStructModel {
 	return SimpleStructModel{
-		mapper: mapper,
-		ID:     yago.NewScalarField(mapper.Table().C(SimpleStructIDColumnName)),
-		Name:   yago.NewScalarField(mapper.Table().C(SimpleStructNameColumnName)),
+		meta:    meta,
+		mapper:  mapper,
+		ID:      yago.NewScalarField(mapper.Table().C(SimpleStructIDColumnName)),
+		Name:    yago.NewScalarField(mapper.Table().C(SimpleStructNameColumnName)),
+		Counter: yago.NewScalarField(mapper.Table().C(SimpleStructCounterColumnName)),
 	}
 }
 
@@ -71,6 +85,27 @@ func (m SimpleStructModel) GetMapper() yago.Mapper {
 	return m.mapper
 }
 
+// Using returns a SimpleStructModel that uses a named mapper, see
+// yago.Metadata.AddNamedMapper. It panics if there is no such mapper, use
+// Lookup to get an error instead.
+func (m SimpleStructModel) Using(name string) SimpleStructModel {
+	model, err := m.Lookup(name)
+	if err != nil {
+		panic(fmt.Sprintf("SimpleStruct has no '%s' mapper", name))
+	}
+	return model
+}
+
+// Lookup returns a SimpleStructModel that uses a named mapper, or
+// yago.ErrMapperNotFound
+func (m SimpleStructModel) Lookup(name string) (SimpleStructModel, error) {
+	mapper, err := m.meta.LookupNamedMapper(SimpleStruct{}, name)
+	if err != nil {
+		return SimpleStructModel{}, err
+	}
+	return newSimpleStructModel(m.meta, mapper), nil
+}
+
 // NewSimpleStructMapper initialize a NewSimpleStructMapper
 func NewSimpleStructMapper() *SimpleStructMapper {
 	m := &SimpleStructMapper{}
@@ -118,6 +153,9 @@ func (mapper SimpleStructMapper) SQLValues(instance yago.MappedStruct, fields ..
 	if allValues || yago.StringListContains(fields, SimpleStructName) {
 		m[SimpleStructNameColumnName] = s.Name
 	}
+	if allValues || yago.StringListContains(fields, SimpleStructCounter) {
+		m[SimpleStructCounterColumnName] = s.Counter
+	}
 	return m
 }
 
@@ -126,6 +164,7 @@ func (mapper SimpleStructMapper) FieldList() []qb.Clause {
 	return []qb.Clause{
 		simpleStructTable.C(SimpleStructIDColumnName),
 		simpleStructTable.C(SimpleStructNameColumnName),
+		simpleStructTable.C(SimpleStructCounterColumnName),
 	}
 }
 
@@ -155,6 +194,7 @@ func (mapper SimpleStructMapper) Scan(rows *sql.Rows, instance yago.MappedStruct
 	if err := rows.Scan(
 		&s.ID,
 		&s.Name,
+		&s.Counter,
 	); err != nil {
 		return err
 	}
@@ -185,6 +225,50 @@ func (mapper SimpleStructMapper) PKeyClause(values []interface{}) qb.Clause {
 	return simpleStructTable.C(SimpleStructIDColumnName).Eq(values[0])
 }
 
+var simpleStructFactorySequence yago.Sequence
+
+// SimpleStructOption sets values on a SimpleStruct built by a SimpleStructFactory
+type SimpleStructOption func(*SimpleStruct)
+
+// SimpleStructFactory builds and inserts valid SimpleStruct structs, for the
+// tests
+type SimpleStructFactory struct {
+	defaults []SimpleStructOption
+}
+
+// NewSimpleStructFactory returns a SimpleStructFactory that applies defaults to
+// the structs it builds
+func NewSimpleStructFactory(defaults ...SimpleStructOption) *SimpleStructFactory {
+	return &SimpleStructFactory{
+		defaults: defaults,
+	}
+}
+
+// Build returns a new SimpleStruct with sequence-based values on its non-null
+// fields, then the factory defaults and opts applied
+func (f *SimpleStructFactory) Build(opts ...SimpleStructOption) *SimpleStruct {
+	s := &SimpleStruct{}
+	n := simpleStructFactorySequence.Next()
+	s.Name = fmt.Sprintf("name %d", n)
+	s.Counter = int(n)
+	for _, opt := range f.defaults {
+		opt(s)
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Create builds a SimpleStruct and inserts it
+func (f *SimpleStructFactory) Create(db yago.IDB, opts ...SimpleStructOption) (*SimpleStruct, error) {
+	s := f.Build(opts...)
+	if err := db.Insert(s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
 const (
 	// BaseStructID is the ID field name
 	BaseStructID = "ID"
@@ -250,7 +334,8 @@ func (PersonStruct) StructType() reflect.Type {
 // PersonStructModel provides direct access to helpers for PersonStruct
 // queries
 type PersonStructModel struct {
-	mapper    *PersonStructMapper
+	meta      *yago.Metadata
+	mapper    yago.Mapper
 	Active    yago.ScalarField
 	FirstName yago.ScalarField
 	LastName  yago.ScalarField
@@ -264,7 +349,12 @@ type PersonStructModel struct {
 func NewPersonStructModel(meta *yago.Metadata) PersonStructModel {
 	mapper := NewPersonStructMapper()
 	meta.AddMapper(mapper)
+	return newPersonStructModel(meta, mapper)
+}
+
+func newPersonStructModel(meta *yago.Metadata, mapper yago.Mapper) PersonStructModel {
 	return PersonStructModel{
+		meta:      meta,
 		mapper:    mapper,
 		Active:    yago.NewScalarField(mapper.Table().C(PersonStructActiveColumnName)),
 		FirstName: yago.NewScalarField(mapper.Table().C(PersonStructFirstNameColumnName)),
@@ -281,6 +371,27 @@ func (m PersonStructModel) GetMapper() yago.Mapper {
 	return m.mapper
 }
 
+// Using returns a PersonStructModel that uses a named mapper, see
+// yago.Metadata.AddNamedMapper. It panics if there is no such mapper, use
+// Lookup to get an error instead.
+func (m PersonStructModel) Using(name string) PersonStructModel {
+	model, err := m.Lookup(name)
+	if err != nil {
+		panic(fmt.Sprintf("PersonStruct has no '%s' mapper", name))
+	}
+	return model
+}
+
+// Lookup returns a PersonStructModel that uses a named mapper, or
+// yago.ErrMapperNotFound
+func (m PersonStructModel) Lookup(name string) (PersonStructModel, error) {
+	mapper, err := m.meta.LookupNamedMapper(PersonStruct{}, name)
+	if err != nil {
+		return PersonStructModel{}, err
+	}
+	return newPersonStructModel(m.meta, mapper), nil
+}
+
 // NewPersonStructMapper initialize a NewPersonStructMapper
 func NewPersonStructMapper() *PersonStructMapper {
 	m := &PersonStructMapper{}
@@ -427,6 +538,51 @@ func (mapper PersonStructMapper) PKeyClause(values []interface{}) qb.Clause {
 	return personStructTable.C(BaseStructIDColumnName).Eq(values[0])
 }
 
+var personStructFactorySequence yago.Sequence
+
+// PersonStructOption sets values on a PersonStruct built by a PersonStructFactory
+type PersonStructOption func(*PersonStruct)
+
+// PersonStructFactory builds and inserts valid PersonStruct structs, for the
+// tests
+type PersonStructFactory struct {
+	defaults []PersonStructOption
+}
+
+// NewPersonStructFactory returns a PersonStructFactory that applies defaults to
+// the structs it builds
+func NewPersonStructFactory(defaults ...PersonStructOption) *PersonStructFactory {
+	return &PersonStructFactory{
+		defaults: defaults,
+	}
+}
+
+// Build returns a new PersonStruct with sequence-based values on its non-null
+// fields, then the factory defaults and opts applied
+func (f *PersonStructFactory) Build(opts ...PersonStructOption) *PersonStruct {
+	s := &PersonStruct{}
+	n := personStructFactorySequence.Next()
+	s.FirstName = fmt.Sprintf("first_name %d", n)
+	s.CreatedAt = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(n) * time.Second)
+	s.UpdatedAt = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(n) * time.Second)
+	for _, opt := range f.defaults {
+		opt(s)
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Create builds a PersonStruct and inserts it
+func (f *PersonStructFactory) Create(db yago.IDB, opts ...PersonStructOption) (*PersonStruct, error) {
+	s := f.Build(opts...)
+	if err := db.Insert(s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
 const (
 	// AutoIncBaseID is the ID field name
 	AutoIncBaseID = "ID"
@@ -470,7 +626,8 @@ func (AutoIncChild) StructType() reflect.Type {
 // AutoIncChildModel provides direct access to helpers for AutoIncChild
 // queries
 type AutoIncChildModel struct {
-	mapper *AutoIncChildMapper
+	meta   *yago.Metadata
+	mapper yago.Mapper
 	Name   yago.ScalarField
 	Person yago.ScalarField
 	ID     yago.ScalarField
@@ -480,7 +637,12 @@ type AutoIncChildModel struct {
 func NewAutoIncChildModel(meta *yago.Metadata) AutoIncChildModel {
 	mapper := NewAutoIncChildMapper()
 	meta.AddMapper(mapper)
+	return newAutoIncChildModel(meta, mapper)
+}
+
+func newAutoIncChildModel(meta *yago.Metadata, mapper yago.Mapper) AutoIncChildModel {
 	return AutoIncChildModel{
+		meta:   meta,
 		mapper: mapper,
 		Name:   yago.NewScalarField(mapper.Table().C(AutoIncChildNameColumnName)),
 		Person: yago.NewScalarField(mapper.Table().C(AutoIncChildPersonColumnName)),
@@ -493,6 +655,27 @@ func (m AutoIncChildModel) GetMapper() yago.Mapper {
 	return m.mapper
 }
 
+// Using returns a AutoIncChildModel that uses a named mapper, see
+// yago.Metadata.AddNamedMapper. It panics if there is no such mapper, use
+// Lookup to get an error instead.
+func (m AutoIncChildModel) Using(name string) AutoIncChildModel {
+	model, err := m.Lookup(name)
+	if err != nil {
+		panic(fmt.Sprintf("AutoIncChild has no '%s' mapper", name))
+	}
+	return model
+}
+
+// Lookup returns a AutoIncChildModel that uses a named mapper, or
+// yago.ErrMapperNotFound
+func (m AutoIncChildModel) Lookup(name string) (AutoIncChildModel, error) {
+	mapper, err := m.meta.LookupNamedMapper(AutoIncChild{}, name)
+	if err != nil {
+		return AutoIncChildModel{}, err
+	}
+	return newAutoIncChildModel(m.meta, mapper), nil
+}
+
 // NewAutoIncChildMapper initialize a NewAutoIncChildMapper
 func NewAutoIncChildMapper() *AutoIncChildMapper {
 	m := &AutoIncChildMapper{}
@@ -611,3 +794,57 @@ func (mapper AutoIncChildMapper) PKey(instance yago.MappedStruct) (values []inte
 func (mapper AutoIncChildMapper) PKeyClause(values []interface{}) qb.Clause {
 	return autoIncChildTable.C(AutoIncBaseIDColumnName).Eq(values[0])
 }
+
+var autoIncChildFactorySequence yago.Sequence
+
+// AutoIncChildOption sets values on a AutoIncChild built by a AutoIncChildFactory
+type AutoIncChildOption func(*AutoIncChild)
+
+// AutoIncChildFactory builds and inserts valid AutoIncChild structs, for the
+// tests
+type AutoIncChildFactory struct {
+	// PersonFactory creates the PersonStruct of Person if it is not set
+	PersonFactory *PersonStructFactory
+	defaults      []AutoIncChildOption
+}
+
+// NewAutoIncChildFactory returns a AutoIncChildFactory that applies defaults to
+// the structs it builds
+func NewAutoIncChildFactory(defaults ...AutoIncChildOption) *AutoIncChildFactory {
+	return &AutoIncChildFactory{
+		PersonFactory: NewPersonStructFactory(),
+		defaults:      defaults,
+	}
+}
+
+// Build returns a new AutoIncChild with sequence-based values on its non-null
+// fields, then the factory defaults and opts applied
+func (f *AutoIncChildFactory) Build(opts ...AutoIncChildOption) *AutoIncChild {
+	s := &AutoIncChild{}
+	n := autoIncChildFactorySequence.Next()
+	s.Name = fmt.Sprintf("name %d", n)
+	for _, opt := range f.defaults {
+		opt(s)
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Create builds a AutoIncChild and inserts it, after creating its required
+// parents that are not set
+func (f *AutoIncChildFactory) Create(db yago.IDB, opts ...AutoIncChildOption) (*AutoIncChild, error) {
+	s := f.Build(opts...)
+	if s.Person == (uuid.UUID{}) {
+		parent, err := f.PersonFactory.Create(db)
+		if err != nil {
+			return nil, err
+		}
+		s.Person = parent.ID
+	}
+	if err := db.Insert(s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
@@ -0,0 +1,112 @@
+package generate
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FactoryDefault returns the expression of the value a factory gives to
+// the field, "n" being the sequence number, or "" if the field keeps its
+// zero value: the nullable, auto incremented and foreign key fields, and the
+// fields of other types than strings, integers and times.
+func (f *FieldData) FactoryDefault() string {
+	if f.Tags.AutoIncrement || f.Tags.TextMarshaled || len(f.Tags.ForeignKeys) != 0 ||
+		strings.Contains(f.ColumnModifiers, ".Null()") {
+		return ""
+	}
+	switch f.Type {
+	case "string":
+		return fmt.Sprintf(`fmt.Sprintf("%s %%d", n)`, f.ColumnName)
+	case "int", "int64", "uint", "uint64":
+		return f.Type + "(n)"
+	case "time.Time":
+		return "time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(n) * time.Second)"
+	}
+	return ""
+}
+
+// FactoryFields returns the fields that have a FactoryDefault
+func (str *StructData) FactoryFields() []*FieldData {
+	var fields []*FieldData
+	for i := range str.Fields {
+		if str.Fields[i].FactoryDefault() != "" {
+			fields = append(fields, &str.Fields[i])
+		}
+	}
+	return fields
+}
+
+// FactoryParent is a required parent that a factory creates
+type FactoryParent struct {
+	FKData
+	// EmptyValue is the zero value of the foreign key field
+	EmptyValue string
+}
+
+// FactoryParents returns the non-null foreign keys to the structs that have
+// a factory, except the ones on a cycle (including the self references),
+// which would make the factories create each other endlessly
+func (str *StructData) FactoryParents() []FactoryParent {
+	var parents []FactoryParent
+	for _, parent := range str.requiredParents() {
+		if !parent.RefTable.requires(str, make(map[*StructData]bool)) {
+			parents = append(parents, parent)
+		}
+	}
+	return parents
+}
+
+// UnsetParents returns the non-null foreign keys that the factory does not
+// create: their parent has no factory or is on a cycle. They are left at
+// their zero value, and must be set by the defaults or the options for
+// Create to succeed.
+func (str *StructData) UnsetParents() []FKData {
+	created := make(map[string]bool)
+	for _, parent := range str.FactoryParents() {
+		created[parent.Column.Name] = true
+	}
+	var unset []FKData
+	for _, fk := range str.ForeignKeys {
+		if !created[fk.Column.Name] && !strings.Contains(fk.Column.ColumnModifiers, ".Null()") {
+			unset = append(unset, fk)
+		}
+	}
+	return unset
+}
+
+// requiredParents returns the non-null foreign keys to the structs that have
+// a factory
+func (str *StructData) requiredParents() []FactoryParent {
+	var parents []FactoryParent
+	for _, fk := range str.ForeignKeys {
+		if !fk.RefTable.Factory ||
+			strings.Contains(fk.Column.ColumnModifiers, ".Null()") ||
+			fk.Column.Type != fk.RefColumn.Type {
+			continue
+		}
+		empty, err := getEmptyValue(fk.Column.Type)
+		if err != nil {
+			continue
+		}
+		parents = append(parents, FactoryParent{FKData: fk, EmptyValue: empty})
+	}
+	return parents
+}
+
+// requires returns true if str is target, or requires it through its
+// required parents
+func (str *StructData) requires(target *StructData, visited map[*StructData]bool) bool {
+	if str == target {
+		return true
+	}
+	if visited[str] {
+		return false
+	}
+	visited[str] = true
+	for _, parent := range str.requiredParents() {
+		if parent.RefTable.requires(target, visited) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package generate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFactoryDefault(t *testing.T) {
+	for _, tt := range []struct {
+		field FieldData
+		value string
+	}{
+		{FieldData{Name: "Name", Type: "string", ColumnName: "name"}, `fmt.Sprintf("name %d", n)`},
+		{FieldData{Name: "Counter", Type: "int"}, "int(n)"},
+		{FieldData{Name: "Date", Type: "time.Time"},
+			"time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(n) * time.Second)"},
+		{FieldData{Name: "Active", Type: "bool"}, ""},
+		{FieldData{Name: "Email", Type: "*string"}, ""},
+		{FieldData{Name: "Name", Type: "string", Tags: ColumnTags{Null: true}}, ""},
+		{FieldData{Name: "ID", Type: "int64", Tags: ColumnTags{PrimaryKey: true, AutoIncrement: true}}, ""},
+		{FieldData{Name: "PersonID", Type: "int64", Tags: ColumnTags{ForeignKeys: []string{"Person"}}}, ""},
+		{FieldData{Name: "Gender", Type: "string", Tags: ColumnTags{TextMarshaled: true}}, ""},
+	} {
+		prepareFieldData(&StructData{Name: "S"}, &tt.field)
+		assert.Equal(t, tt.value, tt.field.FactoryDefault(), tt.field.Name)
+	}
+}
+
+func TestFactoryParents(t *testing.T) {
+	person := &StructData{Name: "Person", Factory: true, Fields: []FieldData{
+		{Name: "ID", Type: "int64", Tags: ColumnTags{PrimaryKey: true, AutoIncrement: true}},
+		{Name: "ParentID", Type: "int64", Tags: ColumnTags{ForeignKeys: []string{"Person"}}},
+	}}
+	phone := &StructData{Name: "Phone", Factory: true, Fields: []FieldData{
+		{Name: "ID", Type: "int64", Tags: ColumnTags{PrimaryKey: true, AutoIncrement: true}},
+		{Name: "PersonID", Type: "int64", Tags: ColumnTags{ForeignKeys: []string{"Person"}}},
+		{Name: "OwnerID", Type: "sql.NullInt64", ColumnType: "qb.BigInt()", Tags: ColumnTags{ForeignKeys: []string{"Person"}}},
+	}}
+	structs := map[string]*StructData{"Person": person, "Phone": phone}
+	for _, str := range structs {
+		prepareStructData(str, FileData{})
+	}
+	postPrepare(&FileData{Imports: make(map[string]bool)}, structs)
+
+	// the self reference and the nullable foreign key are not created
+	assert.Len(t, person.FactoryParents(), 0)
+	if parents := phone.FactoryParents(); assert.Len(t, parents, 1) {
+		assert.Equal(t, "PersonID", parents[0].Column.Name)
+		assert.Equal(t, "0", parents[0].EmptyValue)
+	}
+
+	if unset := person.UnsetParents(); assert.Len(t, unset, 1) {
+		assert.Equal(t, "ParentID", unset[0].Column.Name)
+	}
+	assert.Len(t, phone.UnsetParents(), 0)
+
+	person.Factory = false
+	assert.Len(t, phone.FactoryParents(), 0)
+	if unset := phone.UnsetParents(); assert.Len(t, unset, 1) {
+		assert.Equal(t, "PersonID", unset[0].Column.Name)
+	}
+}
+
+func TestFactoryParentsCycle(t *testing.T) {
+	a := &StructData{Name: "A", Factory: true, Fields: []FieldData{
+		{Name: "ID", Type: "int64", Tags: ColumnTags{PrimaryKey: true, AutoIncrement: true}},
+		{Name: "BID", Type: "int64", Tags: ColumnTags{ForeignKeys: []string{"B"}}},
+	}}
+	b := &StructData{Name: "B", Factory: true, Fields: []FieldData{
+		{Name: "ID", Type: "int64", Tags: ColumnTags{PrimaryKey: true, AutoIncrement: true}},
+		{Name: "AID", Type: "int64", Tags: ColumnTags{ForeignKeys: []string{"A"}}},
+	}}
+	c := &StructData{Name: "C", Factory: true, Fields: []FieldData{
+		{Name: "ID", Type: "int64", Tags: ColumnTags{PrimaryKey: true, AutoIncrement: true}},
+		{Name: "AID", Type: "int64", Tags: ColumnTags{ForeignKeys: []string{"A"}}},
+	}}
+	structs := map[string]*StructData{"A": a, "B": b, "C": c}
+	for _, str := range structs {
+		prepareStructData(str, FileData{})
+	}
+	postPrepare(&FileData{Imports: make(map[string]bool)}, structs)
+
+	// A and B do not create each other
+	assert.Len(t, a.FactoryParents(), 0)
+	assert.Len(t, b.FactoryParents(), 0)
+	// C creates its A, out of the cycle
+	if parents := c.FactoryParents(); assert.Len(t, parents, 1) {
+		assert.Equal(t, "AID", parents[0].Column.Name)
+	}
+	assert.Len(t, a.UnsetParents(), 1)
+	assert.Len(t, c.UnsetParents(), 0)
+}
@@ -7,29 +7,17 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+
+	"github.com/orus-io/yago/internal/tags"
 )
 
 // TypeConf contains column type and empty value of a go type
-type TypeConf struct {
-	ColumnName string
-	EmptyValue string
-}
+type TypeConf = tags.TypeConf
 
 // TypesMap associate column types and empty values to go types.
-// It is used to guess the column type at generation time.
-var TypesMap = map[string]TypeConf{
-	"int":           {"qb.Int()", "0"},
-	"uint":          {"qb.Int().Unsigned()", "0"},
-	"int64":         {"qb.BigInt()", "0"},
-	"uint64":        {"qb.BigInt().Unsigned()", "0"},
-	"string":        {"qb.Varchar()", `""`},
-	"*string":       {"qb.Varchar()", "nil"},
-	"bool":          {"qb.Boolean()", "false"},
-	"time.Time":     {"qb.Timestamp()", "(time.Time{})"},
-	"*time.Time":    {"qb.Timestamp()", "nil"},
-	"uuid.UUID":     {"qb.UUID()", "(uuid.UUID{})"},
-	"uuid.NullUUID": {"qb.UUID()", "(uuid.NullUUID{})"},
-}
+// It is used to guess the column type at generation time, and is shared
+// with the reflect mappers.
+var TypesMap = tags.TypesMap
 
 func guessColumnType(goType string) (string, error) {
 	typeConf, ok := TypesMap[goType]
@@ -141,18 +129,7 @@ func loadEmbedded(path string, structs map[string]*StructData, fd FileData) []*S
 				embedded, ok = otherStructsByName[name]
 			}
 			if ok {
-				for index, fields := range embedded.Indexes {
-					if _, ok := str.Indexes[index]; !ok {
-						str.Indexes[index] = []int{}
-					}
-					for _, fieldIndex := range fields {
-						str.Indexes[index] = append(str.Indexes[index], len(str.Fields)+fieldIndex)
-					}
-				}
-				for _, field := range embedded.Fields {
-					field.FromEmbedded = true
-					str.Fields = append(str.Fields, field)
-				}
+				mergeEmbedded(str, embedded)
 			} else {
 				fmt.Println(
 					"Could not find embedded struct definition for '" + name + "'")
@@ -162,41 +139,36 @@ func loadEmbedded(path string, structs map[string]*StructData, fd FileData) []*S
 	return newStructs
 }
 
-func parseFkDef(fkDef string) (fk string, onUpdate string, onDelete string) {
-	if strings.Index(fkDef, " ") != -1 {
-		tokens := strings.Split(fkDef, " ")
-		fk = tokens[0]
-		for i := 1; i < len(tokens); {
-			token := tokens[i]
-			var event *string
-			switch strings.ToUpper(token) {
-			case "ONUPDATE":
-				event = &onUpdate
-			case "ONDELETE":
-				event = &onDelete
-			default:
-				panic(fmt.Sprintf("Invalid token in fk definition: %s", token))
-			}
-			i++
-			for i < len(tokens) {
-				token := tokens[i]
-				if strings.ToUpper(token) == "ONUPDATE" || strings.ToUpper(token) == "ONDELETE" {
-					break
-				}
-				if *event != "" {
-					*event += " "
-				}
-				*event += token
-				i++
-			}
-			*event = strings.ToUpper(*event)
+// mergeEmbedded appends the fields and the indexes of an embedded struct
+func mergeEmbedded(str *StructData, embedded *StructData) {
+	for index, fields := range embedded.Indexes {
+		if _, ok := str.Indexes[index]; !ok {
+			str.Indexes[index] = []int{}
+		}
+		for _, fieldIndex := range fields {
+			str.Indexes[index] = append(str.Indexes[index], len(str.Fields)+fieldIndex)
 		}
-	} else {
-		fk = fkDef
+	}
+	for _, field := range embedded.Fields {
+		field.FromEmbedded = true
+		str.Fields = append(str.Fields, field)
+	}
+}
+
+func parseFkDef(fkDef string) (fk string, onUpdate string, onDelete string) {
+	fk, onUpdate, onDelete, err := ParseFKDef(fkDef)
+	if err != nil {
+		panic(err.Error())
 	}
 	return
 }
 
+// ParseFKDef parses a foreign key definition ("Struct[.Field] [ONUPDATE
+// action] [ONDELETE action]")
+func ParseFKDef(fkDef string) (fk string, onUpdate string, onDelete string, err error) {
+	return tags.ParseFKDef(fkDef)
+}
+
 func postPrepare(filedata *FileData, structs map[string]*StructData) {
 	for _, str := range structs {
 		for i := range str.Fields {
@@ -268,7 +240,7 @@ func ProcessFile(logger *log.Logger, path string, file string, pack string, outp
 		output = filepath.Join(path, base+"_yago"+ext)
 	}
 
-	filedata := FileData{Package: pack, Imports: make(map[string]bool)}
+	filedata := FileData{Package: pack, StdImports: make(map[string]bool), Imports: make(map[string]bool)}
 
 	structs, err := ParseFile(filepath.Join(path, file))
 	if err != nil {
@@ -291,6 +263,21 @@ func ProcessFile(logger *log.Logger, path string, file string, pack string, outp
 		}
 	}
 	postPrepare(&filedata, structsByName)
+	for _, str := range structs {
+		if !str.Factory {
+			continue
+		}
+		for _, f := range str.FactoryFields() {
+			if f.Type == "time.Time" {
+				filedata.StdImports["time"] = true
+			}
+		}
+		for _, parent := range str.FactoryParents() {
+			if parent.Column.Type == "uuid.UUID" {
+				filedata.Imports["github.com/m4rw3r/uuid"] = true
+			}
+		}
+	}
 
 	outf, err := os.Create(output)
 	if err != nil {
@@ -314,6 +301,12 @@ func ProcessFile(logger *log.Logger, path string, file string, pack string, outp
 			if err := structTemplate.Execute(outf, &str); err != nil {
 				return err
 			}
+			if !str.Factory {
+				continue
+			}
+			if err := factoryTemplate.Execute(outf, &str); err != nil {
+				return err
+			}
 		}
 	}
 
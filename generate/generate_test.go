@@ -1,6 +1,9 @@
 package generate
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -24,3 +27,36 @@ func TestParseFkDef(t *testing.T) {
 		assert.Equal(t, tt.onDelete, onDelete)
 	}
 }
+
+func TestParseFileDirectiveComment(t *testing.T) {
+	dir, err := ioutil.TempDir("", "yago-parse")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "model.go")
+	assert.Nil(t, ioutil.WriteFile(path, []byte(`package model
+
+//yago:autoattrs
+type Person struct {
+	ID int64 `+"`yago:\"primary_key,auto_increment\"`"+`
+}
+
+// Phone is a phone number
+//yago:phone_numbers
+type Phone struct {
+	ID int64 `+"`yago:\"primary_key,auto_increment\"`"+`
+}
+
+// Ignored has no yago comment
+type Ignored struct{}
+`), 0644))
+
+	structs, err := ParseFile(path)
+	assert.Nil(t, err)
+	if assert.Len(t, structs, 2) {
+		assert.Equal(t, "Person", structs[0].Name)
+		assert.Equal(t, "person", structs[0].TableName)
+		assert.Equal(t, "Phone", structs[1].Name)
+		assert.Equal(t, "phone_numbers", structs[1].TableName)
+	}
+}
@@ -13,6 +13,8 @@ import (
 	"reflect"
 	"regexp"
 	"strings"
+
+	"github.com/orus-io/yago/internal/tags"
 )
 
 var magicYagoComment = regexp.MustCompile(`yago:([0-9A-Za-z_\.,]+)?`)
@@ -21,13 +23,7 @@ type structDefArgs struct {
 	TableName string
 	AutoAttrs bool
 	NoTable   bool
-}
-
-func readNameValue(s string) (name string, value string) {
-	l := strings.SplitN(s, "=", 2)
-	name = l[0]
-	value = l[1]
-	return
+	Factory   bool
 }
 
 func magicYagoCommentArgs(doc string) (args structDefArgs, ok bool) {
@@ -43,6 +39,8 @@ func magicYagoCommentArgs(doc string) (args structDefArgs, ok bool) {
 				args.AutoAttrs = true
 			} else if arg == "notable" {
 				args.NoTable = true
+			} else if arg == "factory" {
+				args.Factory = true
 			} else {
 				args.TableName = arg
 			}
@@ -54,42 +52,27 @@ func magicYagoCommentArgs(doc string) (args structDefArgs, ok bool) {
 	return
 }
 
-func readColumnTags(tag string) (tags ColumnTags) {
-	splitted := strings.Split(tag, ",")
-	for _, arg := range splitted {
-		if strings.Index(arg, "=") != -1 {
-			name, value := readNameValue(arg)
-			if name == "index" {
-				tags.Indexes = append(tags.Indexes, value)
-			} else if name == "unique_index" {
-				tags.UniqueIndexes = append(tags.UniqueIndexes, value)
-			} else if name == "fk" {
-				tags.ForeignKeys = append(tags.ForeignKeys, value)
-			} else if name == "type" {
-				tags.Type = value
-			} else {
-				panic(fmt.Sprintf("Invalid tag %v", arg))
-			}
-		} else if arg == "primary_key" {
-			tags.PrimaryKey = true
-		} else if arg == "auto_increment" {
-			tags.AutoIncrement = true
-		} else if arg == "index" {
-			tags.Indexes = append(tags.Indexes, ".")
-		} else if arg == "unique_index" || arg == "unique" {
-			tags.UniqueIndexes = append(tags.UniqueIndexes, ".")
-		} else if arg == "null" {
-			tags.Null = true
-		} else if arg == "notnull" || arg == "not null" {
-			tags.NotNull = true
-		} else if arg == "textmarshaled" {
-			tags.TextMarshaled = true
-		} else if arg == "." {
-		} else {
-			tags.ColumnName = arg
-		}
+// commentText returns the raw text of a comment group. doc.Text() drops
+// the directive-like comments, "//yago:..." included.
+func commentText(doc *ast.CommentGroup) string {
+	var text []string
+	for _, c := range doc.List {
+		text = append(text, c.Text)
 	}
-	return
+	return strings.Join(text, "\n")
+}
+
+func readColumnTags(tag string) ColumnTags {
+	tags, err := ParseColumnTags(tag)
+	if err != nil {
+		panic(err.Error())
+	}
+	return tags
+}
+
+// ParseColumnTags parses the content of a "yago:" field tag
+func ParseColumnTags(tag string) (ColumnTags, error) {
+	return tags.ParseColumnTags(tag)
 }
 
 func getGoType(x ast.Expr) string {
@@ -245,7 +228,7 @@ func ParseFile(path string) ([]*StructData, error) {
 				continue
 			}
 
-			args, ok := magicYagoCommentArgs(doc.Text())
+			args, ok := magicYagoCommentArgs(commentText(doc))
 
 			if !ok {
 				continue
@@ -267,6 +250,7 @@ func ParseFile(path string) ([]*StructData, error) {
 				return nil, err
 			}
 			sd.NoTable = args.NoTable
+			sd.Factory = args.Factory && !args.NoTable
 			if !sd.NoTable {
 				sd.TableName = tablename
 			}
@@ -0,0 +1,333 @@
+package generate
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/orus-io/yago/internal/tags"
+	"github.com/orus-io/yago/schema"
+)
+
+// ReverseField describes a field of a reverse-engineered struct
+type ReverseField struct {
+	Name    string
+	Type    string
+	Tags    []string
+	Comment string
+}
+
+// Tag returns the "yago:" struct tag of the field
+func (f ReverseField) Tag() string {
+	if len(f.Tags) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("`yago:%q`", strings.Join(f.Tags, ","))
+}
+
+// ReverseStruct describes a reverse-engineered struct
+type ReverseStruct struct {
+	Name      string
+	TableName string
+	// MagicArgs are the arguments of the "//yago:" comment
+	MagicArgs string
+	Fields    []ReverseField
+}
+
+// ReverseData is the content of a reverse-engineered file
+type ReverseData struct {
+	Package    string
+	StdImports []string
+	Imports    []string
+	Structs    []ReverseStruct
+	// Skipped lists the tables that are not mapped, and why
+	Skipped []string
+}
+
+var reverseTemplate = template.Must(template.New("reverse").Parse(
+	`package {{ .Package }}
+
+// reverse-engineered with yago from the database schema
+
+import (
+	{{- range .StdImports }}
+	"{{ . }}"
+	{{- end }}
+{{ range .Imports }}
+	"{{ . }}"
+	{{- end }}
+)
+
+//go:generate yago --fmt
+{{ range .Skipped }}
+// {{ . }}
+{{- end }}
+
+// Model gives easy access to the mapped structs
+type Model struct {
+	Meta *yago.Metadata
+{{ range .Structs }}
+	{{ .Name }} {{ .Name }}Model
+{{- end }}
+}
+
+// NewModel initialize a model
+func NewModel() *Model {
+	meta := yago.NewMetadata()
+	return &Model{
+		Meta: meta,
+		{{- range .Structs }}
+		{{ .Name }}: New{{ .Name }}Model(meta),
+		{{- end }}
+	}
+}
+{{ range .Structs }}
+// {{ .Name }} is mapped on the {{ .TableName }} table
+//yago:{{ .MagicArgs }}
+type {{ .Name }} struct {
+	{{- range .Fields }}
+	{{- if .Comment }}
+	// {{ .Comment }}
+	{{- end }}
+	{{ .Name }} {{ .Type }} {{ .Tag }}
+	{{- end }}
+}
+{{ end }}`))
+
+var (
+	nonIdentChars = regexp.MustCompile(`[^0-9A-Za-z_]+`)
+	sizedType     = regexp.MustCompile(`^[A-Z ]+\((\d+)\)$`)
+	initialisms   = make(map[string]bool)
+)
+
+func init() {
+	for _, initialism := range tags.CommonInitialisms {
+		initialisms[initialism] = true
+	}
+}
+
+// ToGoName converts a db name to a go exported name, the reverse of
+// ToDBName
+func ToGoName(name string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(nonIdentChars.ReplaceAllString(name, "_"), "_") {
+		if part == "" {
+			continue
+		}
+		if upper := strings.ToUpper(part); initialisms[upper] {
+			b.WriteString(upper)
+		} else {
+			b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	goName := b.String()
+	if goName == "" || goName[0] >= '0' && goName[0] <= '9' {
+		goName = "X" + goName
+	}
+	return goName
+}
+
+// reverseType returns the go type of a column and, if it cannot be guessed
+// by the generator, the qb type expression
+func reverseType(driver string, col schema.Column) (goType string, qbType string, imp string) {
+	normalized := schema.NormalizeType(driver, col.Type)
+	base := strings.SplitN(normalized, "(", 2)[0]
+	size := ""
+	if sm := sizedType.FindStringSubmatch(normalized); sm != nil && sm[1] != "255" {
+		size = ".Size(" + sm[1] + ")"
+	}
+	nullable := col.Nullable && !col.AutoIncrement
+	switch base {
+	case "INTEGER", "BIGINT":
+		goType = "int64"
+		if nullable {
+			return "sql.NullInt64", "qb.BigInt()", "database/sql"
+		}
+	case "INT", "SMALLINT":
+		goType = "int"
+		if nullable {
+			return "sql.NullInt64", "qb.Int()", "database/sql"
+		}
+	case "VARCHAR", "CHAR", "TEXT":
+		goType = "string"
+		if nullable {
+			goType = "*string"
+		}
+		if base != "VARCHAR" || size != "" {
+			qbType = map[string]string{"VARCHAR": "qb.Varchar()", "CHAR": "qb.Char()", "TEXT": "qb.Text()"}[base] + size
+		}
+	case "BOOLEAN":
+		goType = "bool"
+		if nullable {
+			return "sql.NullBool", "qb.Boolean()", "database/sql"
+		}
+	case "TIMESTAMP", "TIMESTAMPTZ", "DATE":
+		goType, imp = "time.Time", "time"
+		if nullable {
+			goType = "*time.Time"
+		}
+		if base != "TIMESTAMP" {
+			qbType = fmt.Sprintf("qb.Type(%q)", base)
+		}
+	case "UUID":
+		goType, imp = "uuid.UUID", "github.com/m4rw3r/uuid"
+		if nullable {
+			goType = "uuid.NullUUID"
+		}
+	case "FLOAT":
+		if nullable {
+			return "sql.NullFloat64", "qb.Float()", "database/sql"
+		}
+		return "float64", "qb.Float()", ""
+	case "DECIMAL":
+		if nullable {
+			return "sql.NullFloat64", "qb.Decimal()", "database/sql"
+		}
+		return "float64", "qb.Decimal()", ""
+	case "BLOB":
+		return "[]byte", "qb.Blob()", ""
+	default:
+		goType = "string"
+		if nullable {
+			goType = "*string"
+		}
+		qbType = fmt.Sprintf("qb.Type(%q)", col.Type)
+	}
+	return
+}
+
+// reverseIndexName returns the name of an index, usable in a tag
+func reverseIndexName(table string, index schema.Index) string {
+	name := index.Name
+	if name == "" || strings.HasPrefix(name, "sqlite_autoindex_") {
+		prefix := "i_"
+		if index.Unique {
+			prefix = "u_"
+		}
+		name = prefix + table + "_" + strings.Join(index.Columns, "_")
+	}
+	return nonIdentChars.ReplaceAllString(name, "_")
+}
+
+// Reverse builds the structs that map the tables of a schema.
+// The tables without primary key, and the multi-columns foreign keys, are
+// not supported by the generator and are skipped.
+func Reverse(s *schema.Schema, driver string, pkg string) *ReverseData {
+	data := &ReverseData{Package: pkg}
+	imports := map[string]bool{"github.com/orus-io/yago": true}
+
+	structNames := make(map[string]string)
+	for _, t := range s.Tables {
+		if len(t.PrimaryKey) == 0 {
+			data.Skipped = append(data.Skipped,
+				fmt.Sprintf("Table %s has no primary key, it is not mapped", t.Name))
+			continue
+		}
+		structNames[t.Name] = ToGoName(t.Name)
+	}
+
+	for _, t := range s.Tables {
+		structName, ok := structNames[t.Name]
+		if !ok {
+			continue
+		}
+		str := ReverseStruct{Name: structName, TableName: t.Name, MagicArgs: "autoattrs"}
+		if ToDBName(structName) != t.Name {
+			str.MagicArgs = t.Name + ",autoattrs"
+		}
+		fieldNames := make(map[string]string)
+		for _, col := range t.Columns {
+			fieldNames[col.Name] = ToGoName(col.Name)
+		}
+
+		for _, col := range t.Columns {
+			field := ReverseField{Name: fieldNames[col.Name]}
+			var (
+				qbType string
+				imp    string
+			)
+			field.Type, qbType, imp = reverseType(driver, col)
+			if imp != "" {
+				imports[imp] = true
+			}
+			if ToDBName(field.Name) != col.Name {
+				field.Tags = append(field.Tags, col.Name)
+			}
+			for _, pk := range t.PrimaryKey {
+				if pk == col.Name {
+					field.Tags = append(field.Tags, "primary_key")
+					if col.AutoIncrement {
+						field.Tags = append(field.Tags, "auto_increment")
+					}
+				}
+			}
+			if qbType != "" {
+				field.Tags = append(field.Tags, "type="+qbType)
+			}
+			for _, index := range t.Indexes {
+				for _, indexCol := range index.Columns {
+					if indexCol != col.Name {
+						continue
+					}
+					if index.Unique {
+						field.Tags = append(field.Tags, "unique_index="+reverseIndexName(t.Name, index))
+					} else {
+						field.Tags = append(field.Tags, "index="+reverseIndexName(t.Name, index))
+					}
+				}
+			}
+			for _, fk := range t.ForeignKeys {
+				if fk.Columns[0] != col.Name {
+					continue
+				}
+				refStruct, ok := structNames[fk.RefTable]
+				switch {
+				case len(fk.Columns) != 1:
+					field.Comment = fmt.Sprintf("the foreign key (%s) on %s is not supported",
+						strings.Join(fk.Columns, ", "), fk.RefTable)
+					continue
+				case !ok:
+					field.Comment = fmt.Sprintf("the foreign key on %s is not mapped", fk.RefTable)
+					continue
+				}
+				def := "fk=" + refStruct
+				if ref := s.Table(fk.RefTable); len(ref.PrimaryKey) == 0 || ref.PrimaryKey[0] != fk.RefColumns[0] {
+					def += "." + ToGoName(fk.RefColumns[0])
+				}
+				if fk.OnUpdate != "" {
+					def += " ONUPDATE " + fk.OnUpdate
+				}
+				if fk.OnDelete != "" {
+					def += " ONDELETE " + fk.OnDelete
+				}
+				field.Tags = append(field.Tags, def)
+			}
+			str.Fields = append(str.Fields, field)
+		}
+		data.Structs = append(data.Structs, str)
+	}
+
+	for imp := range imports {
+		if strings.Contains(strings.SplitN(imp, "/", 2)[0], ".") {
+			data.Imports = append(data.Imports, imp)
+		} else {
+			data.StdImports = append(data.StdImports, imp)
+		}
+	}
+	sort.Strings(data.StdImports)
+	sort.Strings(data.Imports)
+	return data
+}
+
+// Source renders the go source of the reverse-engineered structs
+func (data *ReverseData) Source() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := reverseTemplate.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
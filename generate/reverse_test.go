@@ -0,0 +1,107 @@
+package generate
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/orus-io/yago/schema"
+)
+
+func TestToGoName(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		goName string
+	}{
+		{"person", "Person"},
+		{"phone_number", "PhoneNumber"},
+		{"person_id", "PersonID"},
+		{"api_url", "APIURL"},
+		{"first-name", "FirstName"},
+		{"2fa", "X2fa"},
+	} {
+		assert.Equal(t, tt.goName, ToGoName(tt.name))
+	}
+}
+
+func TestReverse(t *testing.T) {
+	s := &schema.Schema{Tables: []*schema.Table{
+		{
+			Name: "person",
+			Columns: []schema.Column{
+				{Name: "id", Type: "INTEGER", AutoIncrement: true},
+				{Name: "name", Type: "VARCHAR(40)"},
+				{Name: "email", Type: "VARCHAR(255)", Nullable: true},
+				{Name: "birth", Type: "TIMESTAMP", Nullable: true},
+				{Name: "Age", Type: "INTEGER", Nullable: true},
+			},
+			PrimaryKey: []string{"id"},
+			Indexes: []schema.Index{
+				{Name: "sqlite_autoindex_person_1", Columns: []string{"name"}, Unique: true, Constraint: true},
+			},
+		},
+		{
+			Name: "phone_numbers",
+			Columns: []schema.Column{
+				{Name: "person_id", Type: "INTEGER"},
+				{Name: "number", Type: "VARCHAR(20)"},
+			},
+			PrimaryKey: []string{"person_id", "number"},
+			Indexes: []schema.Index{
+				{Name: "i_phone_number", Columns: []string{"number"}},
+			},
+			ForeignKeys: []schema.ForeignKey{
+				{Columns: []string{"person_id"}, RefTable: "person", RefColumns: []string{"id"}, OnDelete: "CASCADE"},
+			},
+		},
+		{
+			Name:    "log",
+			Columns: []schema.Column{{Name: "message", Type: "TEXT"}},
+		},
+	}}
+
+	data := Reverse(s, "sqlite3", "model")
+	assert.Equal(t, []string{"database/sql", "time"}, data.StdImports)
+	assert.Equal(t, []string{"github.com/orus-io/yago"}, data.Imports)
+	assert.Equal(t, []string{"Table log has no primary key, it is not mapped"}, data.Skipped)
+	if assert.Len(t, data.Structs, 2) {
+		assert.Equal(t, "autoattrs", data.Structs[0].MagicArgs)
+		assert.Equal(t, []ReverseField{
+			{Name: "ID", Type: "int64", Tags: []string{"primary_key", "auto_increment"}},
+			{Name: "Name", Type: "string", Tags: []string{"type=qb.Varchar().Size(40)", "unique_index=u_person_name"}},
+			{Name: "Email", Type: "*string"},
+			{Name: "Birth", Type: "*time.Time"},
+			{Name: "Age", Type: "sql.NullInt64", Tags: []string{"Age", "type=qb.BigInt()"}},
+		}, data.Structs[0].Fields)
+		assert.Equal(t, "PhoneNumbers", data.Structs[1].Name)
+		assert.Equal(t, []string{"primary_key", "fk=Person ONDELETE CASCADE"}, data.Structs[1].Fields[0].Tags)
+	}
+
+	source, err := data.Source()
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(string(source),
+		"\tPersonID int64  `yago:\"primary_key,fk=Person ONDELETE CASCADE\"`\n"), string(source))
+
+	// the output can be fed back to the generator
+	dir, err := ioutil.TempDir("", "yago-reverse")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "model.go"), source, 0644))
+
+	structs, err := ParseFile(filepath.Join(dir, "model.go"))
+	assert.Nil(t, err)
+	if assert.Len(t, structs, 2) {
+		assert.Equal(t, "person", structs[0].TableName)
+		assert.Len(t, structs[0].Fields, 5)
+		assert.Equal(t, "Age", structs[0].Fields[4].ColumnName)
+		assert.Equal(t, "phone_numbers", structs[1].TableName)
+	}
+	assert.Nil(t, ProcessFile(log.New(ioutil.Discard, "", 0), dir, "model.go", "model", "", false))
+	_, err = os.Stat(filepath.Join(dir, "model_yago.go"))
+	assert.Nil(t, err)
+}
@@ -0,0 +1,146 @@
+package generate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/slicebit/qb"
+
+	"github.com/orus-io/yago/internal/tags"
+)
+
+// ParseTypeExpr evaluates a qb type expression, as found in the "type=" tags
+// and in TypesMap, like "qb.Varchar().Size(40)" or `qb.Type("JSONB")`
+func ParseTypeExpr(expr string) (qb.TypeElem, error) {
+	return tags.ParseTypeExpr(expr)
+}
+
+// Table builds the qb table that the generated code declares for a
+// prepared struct (see LoadPackage)
+func (str *StructData) Table() (qb.TableElem, error) {
+	var clauses []qb.TableClause
+	for _, f := range str.Fields {
+		colType, err := ParseTypeExpr(f.ColumnType)
+		if err != nil {
+			return qb.TableElem{}, fmt.Errorf("Failure on field '%s': %s", f.Name, err)
+		}
+		col := qb.Column(f.ColumnName, colType)
+		for _, modifier := range strings.SplitAfter(f.ColumnModifiers, ")") {
+			switch modifier {
+			case ".PrimaryKey()":
+				col = col.PrimaryKey()
+			case ".AutoIncrement()":
+				col = col.AutoIncrement()
+			case ".Null()":
+				col = col.Null()
+			case ".NotNull()":
+				col = col.NotNull()
+			case "":
+			default:
+				return qb.TableElem{}, fmt.Errorf(
+					"Failure on field '%s': Unsupported column modifier '%s'", f.Name, modifier)
+			}
+		}
+		clauses = append(clauses, col)
+	}
+	for _, name := range sortedIndexNames(str.UniqueIndexes) {
+		clauses = append(clauses, qb.UniqueKey(str.columnNames(str.UniqueIndexes[name])...))
+	}
+	for _, fk := range str.ForeignKeys {
+		constraint := qb.ForeignKey(fk.Column.ColumnName).References(
+			fk.RefTable.TableName, fk.RefColumn.ColumnName)
+		if fk.OnUpdate != "" {
+			constraint = constraint.OnUpdate(fk.OnUpdate)
+		}
+		if fk.OnDelete != "" {
+			constraint = constraint.OnDelete(fk.OnDelete)
+		}
+		clauses = append(clauses, constraint)
+	}
+	table := qb.Table(str.TableName, clauses...)
+	for _, name := range sortedIndexNames(str.Indexes) {
+		table = table.Index(str.columnNames(str.Indexes[name])...)
+	}
+	return table, nil
+}
+
+func (str *StructData) columnNames(fields []int) []string {
+	var names []string
+	for _, i := range fields {
+		names = append(names, str.Fields[i].ColumnName)
+	}
+	return names
+}
+
+// sortedIndexNames returns the index names in the order of the generated
+// code
+func sortedIndexNames(indexes map[string][]int) []string {
+	var names []string
+	for name := range indexes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadPackage parses the go files of a directory, except the tests, and
+// returns the structs mapped on a table, prepared like the generator does
+func LoadPackage(path string) (structs []*StructData, err error) {
+	defer func() {
+		// the preparation panics on invalid definitions
+		if r := recover(); r != nil {
+			structs, err = nil, fmt.Errorf("%v", r)
+		}
+	}()
+
+	var all []*StructData
+	err = filepath.Walk(path, func(fpath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if fpath == path {
+				return nil
+			}
+			return filepath.SkipDir
+		}
+		if filepath.Ext(fpath) != ".go" || strings.HasSuffix(fpath, "_test.go") {
+			return nil
+		}
+		fileStructs, err := ParseFile(fpath)
+		if err != nil {
+			return err
+		}
+		all = append(all, fileStructs...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	filedata := FileData{Imports: make(map[string]bool)}
+	byName := make(map[string]*StructData)
+	for _, str := range all {
+		prepareStructData(str, filedata)
+		byName[str.Name] = str
+	}
+	for _, str := range all {
+		for _, name := range str.Embed {
+			if embedded, ok := byName[name]; ok {
+				mergeEmbedded(str, embedded)
+			}
+		}
+	}
+	tables := make(map[string]*StructData)
+	for _, str := range all {
+		if !str.NoTable {
+			tables[str.Name] = str
+			structs = append(structs, str)
+		}
+	}
+	postPrepare(&filedata, tables)
+	return structs, nil
+}
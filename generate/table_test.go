@@ -0,0 +1,81 @@
+package generate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/slicebit/qb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTypeExpr(t *testing.T) {
+	for _, expr := range []string{
+		"qb.Varchar()", "qb.Varchar().Size(40)", "qb.BigInt().Unsigned()", `qb.Type("JSONB")`,
+	} {
+		_, err := ParseTypeExpr(expr)
+		assert.Nil(t, err, expr)
+	}
+	for _, expr := range []string{
+		"qb.Unknown()", "qb.Varchar().Size()", `qb.Varchar("x")`, "Varchar()",
+	} {
+		_, err := ParseTypeExpr(expr)
+		assert.NotNil(t, err, expr)
+	}
+}
+
+const loadPackageSource = `package model
+
+//yago:notable
+type Base struct {
+	ID int64 ` + "`yago:\"primary_key,auto_increment\"`" + `
+}
+
+//yago:autoattrs
+type Person struct {
+	Base
+	Name string ` + "`yago:\"type=qb.Varchar().Size(40),unique_index\"`" + `
+}
+
+//yago:phone_numbers,autoattrs
+type Phone struct {
+	Base
+	PersonID int64 ` + "`yago:\"fk=Person ONDELETE CASCADE,index\"`" + `
+	Number   *string
+}
+`
+
+func TestLoadPackage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "yago-package")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "model.go"), []byte(loadPackageSource), 0644))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "model_test.go"), []byte("package model\n\n//yago:\ntype Ignored struct{}\n"), 0644))
+
+	structs, err := LoadPackage(dir)
+	assert.Nil(t, err)
+	if !assert.Len(t, structs, 2) {
+		return
+	}
+	phone := structs[1]
+	assert.Equal(t, "phone_numbers", phone.TableName)
+	if assert.Len(t, phone.Fields, 3) {
+		assert.Equal(t, "id", phone.Fields[2].ColumnName)
+	}
+	if assert.Len(t, phone.ForeignKeys, 1) {
+		assert.Equal(t, "person", phone.ForeignKeys[0].RefTable.TableName)
+		assert.Equal(t, "CASCADE", phone.ForeignKeys[0].OnDelete)
+	}
+
+	table, err := phone.Table()
+	assert.Nil(t, err)
+	assert.Equal(t, "phone_numbers", table.Name)
+	assert.Len(t, table.Columns, 3)
+	assert.Equal(t, qb.BigInt(), table.Columns["person_id"].Type)
+
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "model.go"),
+		[]byte("package model\n\n//yago:\ntype NoPK struct {\n\tName string `yago:\"index\"`\n}\n"), 0644))
+	_, err = LoadPackage(dir)
+	assert.NotNil(t, err)
+}
@@ -2,28 +2,20 @@ package generate
 
 import (
 	"text/template"
+
+	"github.com/orus-io/yago/internal/tags"
 )
 
 // FileData contains top-level infos for templates
 type FileData struct {
-	Package   string
-	Imports   map[string]bool
-	HasTables bool
+	Package    string
+	StdImports map[string]bool
+	Imports    map[string]bool
+	HasTables  bool
 }
 
 // ColumnTags contains tags set on the fields
-type ColumnTags struct {
-	ColumnName    string
-	Type          string
-	PrimaryKey    bool
-	AutoIncrement bool
-	Null          bool
-	NotNull       bool
-	ForeignKeys   []string
-	Indexes       []string
-	UniqueIndexes []string
-	TextMarshaled bool
-}
+type ColumnTags = tags.ColumnTags
 
 // FieldData describes a field to be mapped
 type FieldData struct {
@@ -63,6 +55,7 @@ type StructData struct {
 	ForeignKeys   []FKData
 
 	NoTable bool
+	Factory bool
 	Embed   []string
 
 	File FileData
@@ -79,6 +72,9 @@ import (
 	"database/sql"
 	"fmt"
 	"reflect"
+	{{- range $k, $_ := .StdImports }}
+	"{{$k}}"
+	{{- end }}
 
 	"github.com/slicebit/qb"
 
@@ -142,7 +138,8 @@ func ({{ .Name }}) StructType() reflect.Type {
 // {{ .Name }}Model provides direct access to helpers for {{ .Name }}
 // queries
 type {{ .Name }}Model struct {
-	mapper *{{ .Name }}Mapper
+	meta   *yago.Metadata
+	mapper yago.Mapper
 	{{- range .Fields }}
 	{{- if .Tags.TextMarshaled }}
 	{{ .Name }} yago.MarshaledScalarField
@@ -156,7 +153,12 @@ type {{ .Name }}Model struct {
 func New{{ .Name }}Model(meta *yago.Metadata) {{ .Name }}Model {
 	mapper := New{{ .Name }}Mapper()
 	meta.AddMapper(mapper)
+	return new{{ .Name }}Model(meta, mapper)
+}
+
+func new{{ .Name }}Model(meta *yago.Metadata, mapper yago.Mapper) {{ .Name }}Model {
 	return {{ .Name }}Model {
+		meta:   meta,
 		mapper: mapper,
 		{{- range .Fields }}
 		{{- if .Tags.TextMarshaled }}
@@ -173,6 +175,27 @@ func (m {{ .Name }}Model) GetMapper() yago.Mapper {
 	return m.mapper
 }
 
+// Using returns a {{ .Name }}Model that uses a named mapper, see
+// yago.Metadata.AddNamedMapper. It panics if there is no such mapper, use
+// Lookup to get an error instead.
+func (m {{ .Name }}Model) Using(name string) {{ .Name }}Model {
+	model, err := m.Lookup(name)
+	if err != nil {
+		panic(fmt.Sprintf("{{ .Name }} has no '%s' mapper", name))
+	}
+	return model
+}
+
+// Lookup returns a {{ .Name }}Model that uses a named mapper, or
+// yago.ErrMapperNotFound
+func (m {{ .Name }}Model) Lookup(name string) ({{ .Name }}Model, error) {
+	mapper, err := m.meta.LookupNamedMapper({{ .Name }}{}, name)
+	if err != nil {
+		return {{ .Name }}Model{}, err
+	}
+	return new{{ .Name }}Model(m.meta, mapper), nil
+}
+
 // New{{ .Name }}Mapper initialize a New{{ .Name }}Mapper
 func New{{ .Name }}Mapper() *{{ .Name }}Mapper {
 	m := &{{ .Name }}Mapper{}
@@ -340,5 +363,80 @@ func (mapper {{ .Name }}Mapper) PKeyClause(values []interface{}) qb.Clause {
 	)
 	{{- end }}
 }
+`))
+	factoryTemplate = template.Must(template.New("factory").Parse(
+		`
+var {{ .PrivateBasename }}FactorySequence yago.Sequence
+
+// {{ .Name }}Option sets values on a {{ .Name }} built by a {{ .Name }}Factory
+type {{ .Name }}Option func(*{{ .Name }})
+
+// {{ .Name }}Factory builds and inserts valid {{ .Name }} structs, for the
+// tests
+{{- if .UnsetParents }}
+//
+// The factory does not create the parents of
+{{- range $i, $fk := .UnsetParents }}{{ if $i }},{{ end }} {{ $fk.Column.Name }}{{ end }}
+// (no factory, or a foreign key cycle): they must be set by the defaults or
+// the options, or Create fails.
+{{- end }}
+type {{ .Name }}Factory struct {
+	{{- range .FactoryParents }}
+	// {{ .Column.Name }}Factory creates the {{ .RefTable.Name }} of {{ .Column.Name }} if it is not set
+	{{ .Column.Name }}Factory *{{ .RefTable.Name }}Factory
+	{{- end }}
+	defaults []{{ .Name }}Option
+}
+
+// New{{ .Name }}Factory returns a {{ .Name }}Factory that applies defaults to
+// the structs it builds
+func New{{ .Name }}Factory(defaults ...{{ .Name }}Option) *{{ .Name }}Factory {
+	return &{{ .Name }}Factory{
+		{{- range .FactoryParents }}
+		{{ .Column.Name }}Factory: New{{ .RefTable.Name }}Factory(),
+		{{- end }}
+		defaults: defaults,
+	}
+}
+
+// Build returns a new {{ .Name }} with sequence-based values on its non-null
+// fields, then the factory defaults and opts applied
+func (f *{{ .Name }}Factory) Build(opts ...{{ .Name }}Option) *{{ .Name }} {
+	s := &{{ .Name }}{}
+	{{- if .FactoryFields }}
+	n := {{ .PrivateBasename }}FactorySequence.Next()
+	{{- range .FactoryFields }}
+	s.{{ .Name }} = {{ .FactoryDefault }}
+	{{- end }}
+	{{- end }}
+	for _, opt := range f.defaults {
+		opt(s)
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Create builds a {{ .Name }} and inserts it
+{{- if .FactoryParents }}, after creating its required
+// parents that are not set
+{{- end }}
+func (f *{{ .Name }}Factory) Create(db yago.IDB, opts ...{{ .Name }}Option) (*{{ .Name }}, error) {
+	s := f.Build(opts...)
+	{{- range .FactoryParents }}
+	if s.{{ .Column.Name }} == {{ .EmptyValue }} {
+		parent, err := f.{{ .Column.Name }}Factory.Create(db)
+		if err != nil {
+			return nil, err
+		}
+		s.{{ .Column.Name }} = parent.{{ .RefColumn.Name }}
+	}
+	{{- end }}
+	if err := db.Insert(s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
 `))
 )
@@ -0,0 +1,107 @@
+// Package tags parses the yago field tags and the qb type expressions, and
+// converts the Go names to database names. It is shared by the code
+// generator and the reflect mapper.
+package tags
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ColumnTags contains tags set on the fields
+type ColumnTags struct {
+	ColumnName    string
+	Type          string
+	PrimaryKey    bool
+	AutoIncrement bool
+	Null          bool
+	NotNull       bool
+	ForeignKeys   []string
+	Indexes       []string
+	UniqueIndexes []string
+	TextMarshaled bool
+}
+
+func readNameValue(s string) (name string, value string) {
+	l := strings.SplitN(s, "=", 2)
+	name = l[0]
+	value = l[1]
+	return
+}
+
+// ParseColumnTags parses the content of a "yago:" field tag
+func ParseColumnTags(tag string) (tags ColumnTags, err error) {
+	splitted := strings.Split(tag, ",")
+	for _, arg := range splitted {
+		if strings.Index(arg, "=") != -1 {
+			name, value := readNameValue(arg)
+			if name == "index" {
+				tags.Indexes = append(tags.Indexes, value)
+			} else if name == "unique_index" {
+				tags.UniqueIndexes = append(tags.UniqueIndexes, value)
+			} else if name == "fk" {
+				tags.ForeignKeys = append(tags.ForeignKeys, value)
+			} else if name == "type" {
+				tags.Type = value
+			} else {
+				return tags, fmt.Errorf("Invalid tag %v", arg)
+			}
+		} else if arg == "primary_key" {
+			tags.PrimaryKey = true
+		} else if arg == "auto_increment" {
+			tags.AutoIncrement = true
+		} else if arg == "index" {
+			tags.Indexes = append(tags.Indexes, ".")
+		} else if arg == "unique_index" || arg == "unique" {
+			tags.UniqueIndexes = append(tags.UniqueIndexes, ".")
+		} else if arg == "null" {
+			tags.Null = true
+		} else if arg == "notnull" || arg == "not null" {
+			tags.NotNull = true
+		} else if arg == "textmarshaled" {
+			tags.TextMarshaled = true
+		} else if arg == "." {
+		} else {
+			tags.ColumnName = arg
+		}
+	}
+	return
+}
+
+// ParseFKDef parses a foreign key definition ("Struct[.Field] [ONUPDATE
+// action] [ONDELETE action]")
+func ParseFKDef(fkDef string) (fk string, onUpdate string, onDelete string, err error) {
+	if strings.Index(fkDef, " ") != -1 {
+		tokens := strings.Split(fkDef, " ")
+		fk = tokens[0]
+		for i := 1; i < len(tokens); {
+			token := tokens[i]
+			var event *string
+			switch strings.ToUpper(token) {
+			case "ONUPDATE":
+				event = &onUpdate
+			case "ONDELETE":
+				event = &onDelete
+			default:
+				err = fmt.Errorf("Invalid token in fk definition: %s", token)
+				return
+			}
+			i++
+			for i < len(tokens) {
+				token := tokens[i]
+				if strings.ToUpper(token) == "ONUPDATE" || strings.ToUpper(token) == "ONDELETE" {
+					break
+				}
+				if *event != "" {
+					*event += " "
+				}
+				*event += token
+				i++
+			}
+			*event = strings.ToUpper(*event)
+		}
+	} else {
+		fk = fkDef
+	}
+	return
+}
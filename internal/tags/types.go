@@ -0,0 +1,80 @@
+package tags
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/slicebit/qb"
+)
+
+// TypeConf contains column type and empty value of a go type
+type TypeConf struct {
+	ColumnName string
+	EmptyValue string
+}
+
+// TypesMap associate column types and empty values to go types. It is used
+// to guess the column type by the generator and by the reflect mappers.
+var TypesMap = map[string]TypeConf{
+	"int":           {"qb.Int()", "0"},
+	"uint":          {"qb.Int().Unsigned()", "0"},
+	"int64":         {"qb.BigInt()", "0"},
+	"uint64":        {"qb.BigInt().Unsigned()", "0"},
+	"string":        {"qb.Varchar()", `""`},
+	"*string":       {"qb.Varchar()", "nil"},
+	"bool":          {"qb.Boolean()", "false"},
+	"time.Time":     {"qb.Timestamp()", "(time.Time{})"},
+	"*time.Time":    {"qb.Timestamp()", "nil"},
+	"uuid.UUID":     {"qb.UUID()", "(uuid.UUID{})"},
+	"uuid.NullUUID": {"qb.UUID()", "(uuid.NullUUID{})"},
+}
+
+var typeConstructors = map[string]func() qb.TypeElem{
+	"Char":      qb.Char,
+	"Varchar":   qb.Varchar,
+	"Text":      qb.Text,
+	"Int":       qb.Int,
+	"SmallInt":  qb.SmallInt,
+	"BigInt":    qb.BigInt,
+	"Float":     qb.Float,
+	"Boolean":   qb.Boolean,
+	"Timestamp": qb.Timestamp,
+	"UUID":      qb.UUID,
+	"Blob":      qb.Blob,
+	"Decimal":   qb.Decimal,
+}
+
+var (
+	typeExpr         = regexp.MustCompile(`^qb\.(\w+)\(("[^"]*")?\)((?:\.\w+\(\d*\))*)$`)
+	typeExprModifier = regexp.MustCompile(`\.(\w+)\((\d*)\)`)
+)
+
+// ParseTypeExpr evaluates a qb type expression, as found in the "type=" tags
+// and in TypesMap, like "qb.Varchar().Size(40)" or `qb.Type("JSONB")`
+func ParseTypeExpr(expr string) (qb.TypeElem, error) {
+	sm := typeExpr.FindStringSubmatch(expr)
+	if sm == nil {
+		return qb.TypeElem{}, fmt.Errorf("Unsupported type expression '%s'", expr)
+	}
+	var t qb.TypeElem
+	if sm[1] == "Type" && sm[2] != "" {
+		t = qb.Type(sm[2][1 : len(sm[2])-1])
+	} else if constructor, ok := typeConstructors[sm[1]]; ok && sm[2] == "" {
+		t = constructor()
+	} else {
+		return qb.TypeElem{}, fmt.Errorf("Unsupported type expression '%s'", expr)
+	}
+	for _, modifier := range typeExprModifier.FindAllStringSubmatch(sm[3], -1) {
+		switch {
+		case modifier[1] == "Unsigned" && modifier[2] == "":
+			t = t.Unsigned()
+		case modifier[1] == "Size" && modifier[2] != "":
+			size, _ := strconv.Atoi(modifier[2])
+			t = t.Size(size)
+		default:
+			return qb.TypeElem{}, fmt.Errorf("Unsupported type expression '%s'", expr)
+		}
+	}
+	return t, nil
+}
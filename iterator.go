@@ -0,0 +1,42 @@
+package yago
+
+import (
+	"database/sql"
+)
+
+// Iterator iterates over the results of a query
+type Iterator struct {
+	rows   *sql.Rows
+	mapper Mapper
+}
+
+// Iter runs the query and returns an iterator on its results.
+// The iterator must be closed after use.
+func (q Query) Iter() (*Iterator, error) {
+	rows, err := q.SQLQuery()
+	if err != nil {
+		return nil, err
+	}
+	return &Iterator{rows: rows, mapper: q.mapper}, nil
+}
+
+// Next prepares the next result for Scan. It returns false if there is
+// no more results or if an error occured, see Err.
+func (it *Iterator) Next() bool {
+	return it.rows.Next()
+}
+
+// Scan loads the current result in a struct
+func (it *Iterator) Scan(s MappedStruct) error {
+	return it.mapper.Scan(it.rows, s)
+}
+
+// Err returns the error, if any, that was encountered during iteration
+func (it *Iterator) Err() error {
+	return it.rows.Err()
+}
+
+// Close closes the iterator
+func (it *Iterator) Close() error {
+	return it.rows.Close()
+}
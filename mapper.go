@@ -14,6 +14,8 @@ type MapperProvider interface {
 
 // Mapper links a mapped struct and table definition
 type Mapper interface {
+	MapperProvider
+
 	Name() string
 	Table() *qb.TableElem
 	StructType() reflect.Type
@@ -1,16 +1,26 @@
 package yago
 
 import (
+	"fmt"
 	"reflect"
 
 	"github.com/slicebit/qb"
+
+	"github.com/orus-io/yago/schema"
 )
 
 // Metadata holds the table defs & mappers of a db
 type Metadata struct {
 	qbMeta *qb.MetaDataElem
 	// store multiple mappers for structs, with a default one
-	mappers map[reflect.Type]Mapper
+	mappers      map[reflect.Type]Mapper
+	namedMappers map[reflect.Type]map[string]Mapper
+	// all the mappers, in registration order
+	registered []Mapper
+
+	schema      string
+	tablePrefix string
+	tableNames  map[string]bool
 }
 
 // NewMetadata instanciate a Metadata
@@ -21,14 +31,26 @@ func NewMetadata() *Metadata {
 // NewMetadataFromQbMetadata returns a Metadata from a qb.Metadata
 func NewMetadataFromQbMetadata(qbMeta *qb.MetaDataElem) *Metadata {
 	return &Metadata{
-		qbMeta:  qbMeta,
-		mappers: make(map[reflect.Type]Mapper),
+		qbMeta:       qbMeta,
+		mappers:      make(map[reflect.Type]Mapper),
+		namedMappers: make(map[reflect.Type]map[string]Mapper),
+		tableNames:   make(map[string]bool),
 	}
 }
 
-// AddMapper add a mapper
+// AddMapper add a mapper. It replaces the default mapper of the struct, if
+// any.
 func (m *Metadata) AddMapper(mapper Mapper) {
-	m.qbMeta.AddTable(*mapper.Table())
+	m.addTable(mapper.Table())
+	if previous, ok := m.mappers[mapper.StructType()]; ok {
+		for i := range m.registered {
+			if m.registered[i] == previous {
+				m.registered[i] = mapper
+			}
+		}
+	} else {
+		m.registered = append(m.registered, mapper)
+	}
 	m.mappers[mapper.StructType()] = mapper
 }
 
@@ -37,7 +59,101 @@ func (m *Metadata) GetMapper(s MappedStruct) Mapper {
 	return m.mappers[s.StructType()]
 }
 
+// AddNamedMapper add a named mapper, which is an alternative to the default
+// mapper of a struct (see NewTableMapper). The default mapper of the struct
+// must be registered first.
+func (m *Metadata) AddNamedMapper(name string, mapper Mapper) {
+	structType := mapper.StructType()
+	if _, ok := m.mappers[structType]; !ok {
+		panic(fmt.Sprintf(
+			"yago Metadata.AddNamedMapper(): No default mapper for %s", structType))
+	}
+	if m.namedMappers[structType] == nil {
+		m.namedMappers[structType] = make(map[string]Mapper)
+	}
+	if _, ok := m.namedMappers[structType][name]; ok {
+		panic(fmt.Sprintf(
+			"yago Metadata.AddNamedMapper(): %s already has a '%s' mapper", structType, name))
+	}
+	m.addTable(mapper.Table())
+	m.namedMappers[structType][name] = mapper
+	m.registered = append(m.registered, mapper)
+}
+
+// GetNamedMapper returns a named mapper of a mapped struct, or nil
+func (m *Metadata) GetNamedMapper(s MappedStruct, name string) Mapper {
+	return m.namedMappers[s.StructType()][name]
+}
+
+// LookupNamedMapper returns a named mapper of a mapped struct, or
+// ErrMapperNotFound
+func (m *Metadata) LookupNamedMapper(s MappedStruct, name string) (Mapper, error) {
+	if s == nil {
+		return nil, ErrMapperNotFound
+	}
+	mapper, ok := m.namedMappers[s.StructType()][name]
+	if !ok {
+		return nil, ErrMapperNotFound
+	}
+	return mapper, nil
+}
+
+// LookupMapper returns the default mapper of a mapped struct, or
+// ErrMapperNotFound
+func (m *Metadata) LookupMapper(s MappedStruct) (Mapper, error) {
+	if s == nil {
+		return nil, ErrMapperNotFound
+	}
+	mapper, ok := m.mappers[s.StructType()]
+	if !ok {
+		return nil, ErrMapperNotFound
+	}
+	return mapper, nil
+}
+
+// MapperByName returns the mapper that has the given name (see
+// Mapper.Name), or nil
+func (m *Metadata) MapperByName(name string) Mapper {
+	for _, mapper := range m.registered {
+		if mapper.Name() == name {
+			return mapper
+		}
+	}
+	return nil
+}
+
+// MapperByTable returns the mapper of a table, or nil
+func (m *Metadata) MapperByTable(tableName string) Mapper {
+	for _, mapper := range m.registered {
+		if mapper.Table().Name == tableName {
+			return mapper
+		}
+	}
+	return nil
+}
+
+// Mappers returns all the mappers, default and named ones, in their
+// registration order
+func (m *Metadata) Mappers() []Mapper {
+	return append([]Mapper(nil), m.registered...)
+}
+
 // GetQbMetadata returns the underlying
 func (m *Metadata) GetQbMetadata() *qb.MetaDataElem {
 	return m.qbMeta
 }
+
+// DDL returns the statements that create the tables and their indexes, the
+// referenced tables first, for a sqlite3, postgres or mysql dialect. The
+// schema and the table prefix are applied.
+func (m *Metadata) DDL(dialect qb.Dialect) []string {
+	return m.schemaOf(dialect, m.qbMeta.Tables()...).DDL(dialect)
+}
+
+// schemaOf describes tables in the schema and with the table prefix of the
+// metadata
+func (m *Metadata) schemaOf(dialect qb.Dialect, tables ...qb.TableElem) *schema.Schema {
+	s := schema.FromTables(dialect, m.tablePrefix, tables...)
+	s.Name = m.schema
+	return s
+}
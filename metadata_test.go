@@ -0,0 +1,159 @@
+package yago_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/orus-io/yago"
+	"github.com/slicebit/qb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNamedMapper(t *testing.T) {
+	db, model, cleanup := initModel(t)
+	defer cleanup()
+
+	archive := yago.NewTableMapper(model.SimpleStruct.GetMapper(), "archive", "simple_struct_archive")
+	db.Metadata.AddNamedMapper("archive", archive)
+	_, err := db.Engine.DB().Exec(archive.Table().Create(db.Engine.Dialect()))
+	assert.Nil(t, err)
+
+	assert.Equal(t, archive, db.Metadata.GetNamedMapper(&SimpleStruct{}, "archive"))
+	assert.Nil(t, db.Metadata.GetNamedMapper(&SimpleStruct{}, "other"))
+	assert.Panics(t, func() { model.SimpleStruct.Using("other") })
+	_, err = model.SimpleStruct.Lookup("other")
+	assert.Equal(t, yago.ErrMapperNotFound, err)
+	_, err = db.Metadata.LookupNamedMapper(&SimpleStruct{}, "other")
+	assert.Equal(t, yago.ErrMapperNotFound, err)
+	lookedUp, err := model.SimpleStruct.Lookup("archive")
+	assert.Nil(t, err)
+	assert.Equal(t, archive, lookedUp.GetMapper())
+	assert.Panics(t, func() { db.Metadata.AddNamedMapper("archive", archive) })
+
+	live := SimpleStruct{Name: "live"}
+	assert.Nil(t, db.Insert(&live))
+	archived := SimpleStruct{Name: "archived"}
+	assert.Nil(t, db.InsertWith(archive, &archived))
+	assert.NotEqual(t, int64(0), archived.ID)
+
+	m := model.SimpleStruct.Using("archive")
+	var all []SimpleStruct
+	assert.Nil(t, db.Query(m).All(&all))
+	if assert.Len(t, all, 1) {
+		assert.Equal(t, "archived", all[0].Name)
+	}
+
+	var s SimpleStruct
+	assert.Nil(t, db.Query(m).Where(m.Name.Eq("archived")).One(&s))
+	assert.Equal(t, archived.ID, s.ID)
+	assert.Nil(t, db.Query(m).Get(&s, archived.ID))
+
+	s.Name = "renamed"
+	assert.Nil(t, db.UpdateWith(m, &s))
+	assert.Nil(t, db.Query(m).Where(m.Name.Eq("renamed")).One(&s))
+	assert.Nil(t, db.Query(model.SimpleStruct).Where(model.SimpleStruct.Name.Eq("live")).One(&s))
+
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	assert.Nil(t, tx.DeleteWith(m, &archived))
+	assert.Nil(t, tx.Commit())
+
+	var count int
+	assert.Nil(t, db.Query(m).Count(&count))
+	assert.Equal(t, 0, count)
+	assert.Nil(t, db.Query(model.SimpleStruct).Count(&count))
+	assert.Equal(t, 1, count)
+
+	err = db.InsertWith(model.PersonStruct, &SimpleStruct{})
+	if assert.NotNil(t, err) {
+		assert.Equal(t,
+			"yago InsertWith: Mapper 'yago_test/PersonStruct' cannot map a yago_test.SimpleStruct",
+			err.Error())
+	}
+}
+
+func TestValidate(t *testing.T) {
+	meta := yago.NewMetadata()
+	NewFixtureModel(meta)
+	assert.Nil(t, meta.Validate())
+
+	meta = yago.NewMetadata()
+	NewAutoIncChildModel(meta)
+	err := meta.Validate()
+	if assert.IsType(t, &yago.ValidationError{}, err) {
+		assert.Equal(t, []string{
+			"table 'auto_inc_child': foreign key references unknown table 'person_struct'",
+		}, err.(*yago.ValidationError).Problems)
+	}
+}
+
+type unmappedStruct struct{}
+
+func (unmappedStruct) StructType() reflect.Type {
+	return reflect.TypeOf(unmappedStruct{})
+}
+
+func TestMapperLookups(t *testing.T) {
+	meta := yago.NewMetadata()
+	model := NewFixtureModel(meta)
+
+	mapper, err := meta.LookupMapper(&PersonStruct{})
+	assert.Nil(t, err)
+	assert.Equal(t, model.PersonStruct.GetMapper(), mapper)
+
+	_, err = meta.LookupMapper(unmappedStruct{})
+	assert.Equal(t, yago.ErrMapperNotFound, err)
+	_, err = meta.LookupMapper(nil)
+	assert.Equal(t, yago.ErrMapperNotFound, err)
+
+	assert.Equal(t, mapper, meta.MapperByName("yago_test/PersonStruct"))
+	assert.Nil(t, meta.MapperByName("yago_test/Unknown"))
+	assert.Equal(t, mapper, meta.MapperByTable("person_struct"))
+	assert.Nil(t, meta.MapperByTable("unknown"))
+
+	archive := yago.NewTableMapper(model.SimpleStruct.GetMapper(), "archive", "simple_struct_archive")
+	meta.AddNamedMapper("archive", archive)
+	assert.Equal(t, archive, meta.MapperByTable("simple_struct_archive"))
+	assert.Equal(t, archive, meta.MapperByName("yago_test/SimpleStruct:archive"))
+
+	mappers := meta.Mappers()
+	assert.Len(t, mappers, len(meta.GetQbMetadata().Tables()))
+	assert.Equal(t, archive, mappers[len(mappers)-1])
+	assert.Nil(t, meta.Validate())
+
+	// registering a struct again replaces its mapper
+	other := NewPersonStructMapper()
+	meta.AddMapper(other)
+	assert.Len(t, meta.Mappers(), len(mappers))
+	assert.Equal(t, other, meta.MapperByTable("person_struct"))
+	assert.Equal(t, other, meta.GetMapper(&PersonStruct{}))
+}
+
+func TestUnmappedStruct(t *testing.T) {
+	db, _, cleanup := initModel(t)
+	defer cleanup()
+
+	assert.Equal(t, yago.ErrMapperNotFound, db.Insert(unmappedStruct{}))
+	assert.Equal(t, yago.ErrMapperNotFound, db.Update(unmappedStruct{}))
+	assert.Equal(t, yago.ErrMapperNotFound, db.Delete(unmappedStruct{}))
+
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+	assert.Equal(t, yago.ErrMapperNotFound, tx.Insert(unmappedStruct{}))
+	assert.Equal(t, yago.ErrMapperNotFound, tx.UpdateExpr(unmappedStruct{}))
+}
+
+func TestMetadataDDL(t *testing.T) {
+	meta := yago.NewMetadata()
+	meta.SetTablePrefix("acme_")
+	NewAutoIncChildModel(meta)
+	NewPersonStructModel(meta)
+
+	ddl := meta.DDL(qb.NewDialect("sqlite3"))
+	if assert.Len(t, ddl, 2) {
+		assert.True(t, strings.HasPrefix(ddl[0], "CREATE TABLE acme_person_struct ("), ddl[0])
+		assert.True(t, strings.HasPrefix(ddl[1], "CREATE TABLE acme_auto_inc_child ("), ddl[1])
+	}
+}
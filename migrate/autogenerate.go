@@ -0,0 +1,71 @@
+package migrate
+
+import (
+	"strings"
+
+	"github.com/orus-io/yago"
+	"github.com/orus-io/yago/schema"
+)
+
+// Diff introspects the database and returns the changes that make it match
+// the tables of meta. The migrations tables are ignored.
+func (m *Migrator) Diff(meta *yago.Metadata) ([]schema.Change, error) {
+	current, err := schema.Inspect(m.engine.DB(), m.engine.Dialect().Driver(), meta.Schema())
+	if err != nil {
+		return nil, err
+	}
+	var tables []*schema.Table
+	for _, t := range current.Tables {
+		if t.Name != m.TableName && t.Name != m.TableName+"_lock" {
+			tables = append(tables, t)
+		}
+	}
+	current.Tables = tables
+
+	dialect := m.engine.Dialect()
+	target := schema.FromTables(dialect, meta.TablePrefix(), meta.GetQbMetadata().Tables()...)
+	return schema.Diff(current, target, dialect), nil
+}
+
+// Autogenerate writes in dir a migration that makes the database match the
+// tables of meta, and returns the changes. Nothing is written if there is
+// no change.
+// The changes that may lose data, or that the database cannot do, are
+// flagged with a "-- REVIEW" comment and must be checked before applying
+// the migration.
+func (m *Migrator) Autogenerate(meta *yago.Metadata, dir string, version int64, name string) ([]schema.Change, error) {
+	changes, err := m.Diff(meta)
+	if err != nil || len(changes) == 0 {
+		return nil, err
+	}
+
+	var up, down []string
+	for i := range changes {
+		up = append(up, formatChange(changes[i], changes[i].Up))
+		c := changes[len(changes)-1-i]
+		down = append(down, formatChange(c, c.Down))
+	}
+	header := "-- generated by yago from the differences between the Metadata and the database\n\n"
+	_, _, err = writeMigration(dir, version, name,
+		header+strings.Join(up, "\n"), header+strings.Join(down, "\n"))
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+func formatChange(c schema.Change, statements []string) string {
+	var b strings.Builder
+	if c.Review != "" {
+		b.WriteString("-- REVIEW")
+		if c.Destructive {
+			b.WriteString(" (destructive)")
+		}
+		b.WriteString(": " + c.Review + "\n")
+	}
+	b.WriteString("-- " + c.String() + "\n")
+	for _, stmt := range statements {
+		b.WriteString(stmt + ";\n")
+	}
+	return b.String()
+}
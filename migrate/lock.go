@@ -0,0 +1,71 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"time"
+)
+
+// lockKey returns the postgres advisory lock key of the migrations table
+func (m *Migrator) lockKey() int64 {
+	h := fnv.New64a()
+	h.Write([]byte(m.TableName))
+	return int64(h.Sum64())
+}
+
+func (m *Migrator) lockTable() string {
+	return m.engine.Dialect().Escape(m.TableName + "_lock")
+}
+
+// lock acquires the migrations lock on conn.
+// On postgres, it waits for a session advisory lock. On the other
+// databases, it inserts a row in the lock table and returns ErrLocked if
+// the row already exists.
+func (m *Migrator) lock(ctx context.Context, conn *sql.Conn) error {
+	if m.engine.Dialect().Driver() == "postgres" {
+		_, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", m.lockKey())
+		return err
+	}
+	if _, err := conn.ExecContext(ctx, fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s (id INTEGER NOT NULL PRIMARY KEY, locked_at TIMESTAMP NOT NULL)",
+		m.lockTable(),
+	)); err != nil {
+		return err
+	}
+	_, err := conn.ExecContext(ctx, fmt.Sprintf(
+		"INSERT INTO %s (id, locked_at) VALUES (1, ?)", m.lockTable()), time.Now().UTC())
+	if err != nil {
+		var count int
+		if countErr := conn.QueryRowContext(ctx, fmt.Sprintf(
+			"SELECT COUNT(*) FROM %s", m.lockTable())).Scan(&count); countErr == nil && count != 0 {
+			return ErrLocked
+		}
+	}
+	return err
+}
+
+func (m *Migrator) unlock(ctx context.Context, conn *sql.Conn) error {
+	if m.engine.Dialect().Driver() == "postgres" {
+		_, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", m.lockKey())
+		return err
+	}
+	_, err := conn.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s", m.lockTable()))
+	return err
+}
+
+// ForceUnlock releases a lock left by a runner that crashed. It has no
+// effect on postgres, where the lock is released with the connection.
+func (m *Migrator) ForceUnlock() error {
+	if m.engine.Dialect().Driver() == "postgres" {
+		return nil
+	}
+	ctx := context.Background()
+	conn, err := m.engine.DB().Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return m.unlock(ctx, conn)
+}
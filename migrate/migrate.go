@@ -0,0 +1,338 @@
+// Package migrate runs versioned schema migrations.
+//
+// A migration is either a pair of SQL scripts (see Migrator.LoadDir) or a
+// pair of Go functions. Each migration is applied or reverted in its own
+// transaction, together with its bookkeeping row in the migrations table.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/slicebit/qb"
+)
+
+// DefaultTableName is the default name of the migrations bookkeeping table
+const DefaultTableName = "yago_migrations"
+
+var (
+	// ErrLocked is returned if another runner holds the migrations lock
+	ErrLocked = errors.New("yago.migrate.Locked")
+
+	// ErrNoDown is returned when reverting a migration that has no down
+	// step
+	ErrNoDown = errors.New("yago.migrate.NoDown")
+)
+
+// Migration is a versioned schema change. If both are set, the Go function
+// has precedence on the SQL script.
+type Migration struct {
+	Version int64
+	Name    string
+
+	UpSQL    string
+	DownSQL  string
+	UpFunc   func(tx *sql.Tx) error
+	DownFunc func(tx *sql.Tx) error
+}
+
+func (mig Migration) String() string {
+	return fmt.Sprintf("%d_%s", mig.Version, mig.Name)
+}
+
+// Reversible returns true if the migration has a down step
+func (mig Migration) Reversible() bool {
+	return mig.DownFunc != nil || mig.DownSQL != ""
+}
+
+func (mig Migration) run(tx *sql.Tx, script string, f func(tx *sql.Tx) error) error {
+	if f != nil {
+		return f(tx)
+	}
+	if script == "" {
+		return nil
+	}
+	_, err := tx.Exec(script)
+	return err
+}
+
+// Status is the state of a migration
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+	// Missing is true if the migration is applied but unknown by the
+	// migrator
+	Missing bool
+}
+
+// Migrator applies and reverts the migrations
+type Migrator struct {
+	// TableName is the name of the bookkeeping table. A "_lock" suffixed
+	// table is used for locking on databases other than postgres.
+	TableName string
+
+	engine     *qb.Engine
+	migrations []Migration
+}
+
+// New returns a Migrator
+func New(engine *qb.Engine) *Migrator {
+	return &Migrator{
+		TableName: DefaultTableName,
+		engine:    engine,
+	}
+}
+
+// Add adds migrations
+func (m *Migrator) Add(migrations ...Migration) error {
+	for _, mig := range migrations {
+		if mig.Version <= 0 {
+			return fmt.Errorf("yago migrate: Invalid version %d", mig.Version)
+		}
+		for _, other := range m.migrations {
+			if other.Version == mig.Version {
+				return fmt.Errorf("yago migrate: Duplicate version %d (%s and %s)",
+					mig.Version, other.Name, mig.Name)
+			}
+		}
+		m.migrations = append(m.migrations, mig)
+	}
+	sort.Slice(m.migrations, func(i, j int) bool {
+		return m.migrations[i].Version < m.migrations[j].Version
+	})
+	return nil
+}
+
+// AddSQL adds a migration made of SQL scripts. down can be empty.
+func (m *Migrator) AddSQL(version int64, name string, up string, down string) error {
+	return m.Add(Migration{Version: version, Name: name, UpSQL: up, DownSQL: down})
+}
+
+// AddFunc adds a migration made of Go functions. down can be nil.
+func (m *Migrator) AddFunc(version int64, name string, up func(tx *sql.Tx) error, down func(tx *sql.Tx) error) error {
+	return m.Add(Migration{Version: version, Name: name, UpFunc: up, DownFunc: down})
+}
+
+// Migrations returns the migrations, ordered by version
+func (m *Migrator) Migrations() []Migration {
+	return append([]Migration(nil), m.migrations...)
+}
+
+func (m *Migrator) table() qb.TableElem {
+	return qb.Table(
+		m.TableName,
+		qb.Column("version", qb.BigInt()).PrimaryKey().NotNull(),
+		qb.Column("name", qb.Varchar().Size(255)).NotNull(),
+		qb.Column("applied_at", qb.Timestamp()).NotNull(),
+	)
+}
+
+// withConn runs f on a dedicated connection holding the migrations lock
+func (m *Migrator) withConn(f func(ctx context.Context, conn *sql.Conn) error) (err error) {
+	ctx := context.Background()
+	conn, err := m.engine.DB().Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := m.lock(ctx, conn); err != nil {
+		return err
+	}
+	defer func() {
+		if unlockErr := m.unlock(ctx, conn); err == nil {
+			err = unlockErr
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s (version BIGINT NOT NULL PRIMARY KEY, name VARCHAR(255) NOT NULL, applied_at TIMESTAMP NOT NULL)",
+		m.engine.Dialect().Escape(m.TableName),
+	)); err != nil {
+		return err
+	}
+	return f(ctx, conn)
+}
+
+func (m *Migrator) exec(ctx context.Context, e interface {
+	ExecContext(context.Context, string, ...interface{}) (sql.Result, error)
+}, builder qb.Builder) error {
+	stmt := builder.Build(m.engine.Dialect())
+	_, err := e.ExecContext(ctx, stmt.SQL(), stmt.Bindings()...)
+	return err
+}
+
+// applied returns the applied migrations versions and dates
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[int64]Status, error) {
+	table := m.table()
+	stmt := qb.Select(table.C("version"), table.C("name"), table.C("applied_at")).
+		From(table).
+		Build(m.engine.Dialect())
+	rows, err := conn.QueryContext(ctx, stmt.SQL(), stmt.Bindings()...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]Status)
+	for rows.Next() {
+		s := Status{Applied: true}
+		if err := rows.Scan(&s.Version, &s.Name, &s.AppliedAt); err != nil {
+			return nil, err
+		}
+		applied[s.Version] = s
+	}
+	return applied, rows.Err()
+}
+
+// Status returns the state of all the known and applied migrations,
+// ordered by version
+func (m *Migrator) Status() (status []Status, err error) {
+	err = m.withConn(func(ctx context.Context, conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			s, ok := applied[mig.Version]
+			if !ok {
+				s = Status{Version: mig.Version}
+			}
+			s.Name = mig.Name
+			status = append(status, s)
+			delete(applied, mig.Version)
+		}
+		for _, s := range applied {
+			s.Missing = true
+			status = append(status, s)
+		}
+		return nil
+	})
+	sort.Slice(status, func(i, j int) bool {
+		return status[i].Version < status[j].Version
+	})
+	return
+}
+
+// Up applies all the pending migrations
+func (m *Migrator) Up() ([]Migration, error) {
+	return m.UpTo(0)
+}
+
+// UpTo applies the pending migrations up to version included. If version
+// is 0, all the pending migrations are applied.
+func (m *Migrator) UpTo(version int64) (done []Migration, err error) {
+	err = m.withConn(func(ctx context.Context, conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		table := m.table()
+		for _, mig := range m.migrations {
+			if version != 0 && mig.Version > version {
+				break
+			}
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			err := m.inTx(ctx, conn, func(tx *sql.Tx) error {
+				if err := mig.run(tx, mig.UpSQL, mig.UpFunc); err != nil {
+					return err
+				}
+				return m.exec(ctx, tx, table.Insert().Values(map[string]interface{}{
+					"version":    mig.Version,
+					"name":       mig.Name,
+					"applied_at": time.Now().UTC(),
+				}))
+			})
+			if err != nil {
+				return fmt.Errorf("yago migrate: Migration %s failed: %s", mig, err)
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return
+}
+
+// Down reverts the last applied migration
+func (m *Migrator) Down() ([]Migration, error) {
+	return m.down(-1)
+}
+
+// DownTo reverts the applied migrations having a version greater than
+// version. If version is 0, all the migrations are reverted.
+func (m *Migrator) DownTo(version int64) ([]Migration, error) {
+	return m.down(version)
+}
+
+// down reverts the migrations above version, or the last one if version
+// is negative
+func (m *Migrator) down(version int64) (done []Migration, err error) {
+	err = m.withConn(func(ctx context.Context, conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		var versions []int64
+		for v := range applied {
+			if v > version {
+				versions = append(versions, v)
+			}
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+		if version < 0 && len(versions) > 1 {
+			versions = versions[:1]
+		}
+
+		table := m.table()
+		for _, v := range versions {
+			mig, ok := m.find(v)
+			if !ok {
+				return fmt.Errorf("yago migrate: Migration %d is applied but unknown", v)
+			}
+			if !mig.Reversible() {
+				return fmt.Errorf("yago migrate: Migration %s: %s", mig, ErrNoDown)
+			}
+			err := m.inTx(ctx, conn, func(tx *sql.Tx) error {
+				if err := mig.run(tx, mig.DownSQL, mig.DownFunc); err != nil {
+					return err
+				}
+				return m.exec(ctx, tx, table.Delete().Where(table.C("version").Eq(mig.Version)))
+			})
+			if err != nil {
+				return fmt.Errorf("yago migrate: Migration %s failed: %s", mig, err)
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return
+}
+
+func (m *Migrator) find(version int64) (Migration, bool) {
+	for _, mig := range m.migrations {
+		if mig.Version == version {
+			return mig, true
+		}
+	}
+	return Migration{}, false
+}
+
+func (m *Migrator) inTx(ctx context.Context, conn *sql.Conn, f func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := f(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,237 @@
+package migrate_test
+
+import (
+	"database/sql"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/slicebit/qb"
+	_ "github.com/slicebit/qb/dialects/sqlite"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/orus-io/yago"
+	"github.com/orus-io/yago/migrate"
+	"github.com/orus-io/yago/schema"
+)
+
+func initMigrator(t *testing.T) (*qb.Engine, *migrate.Migrator) {
+	engine, err := qb.New("sqlite3", ":memory:")
+	assert.Nil(t, err)
+	engine.DB().SetMaxOpenConns(1)
+	return engine, migrate.New(engine)
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	assert.Nil(t, ioutil.WriteFile(path, []byte(content), 0644))
+}
+
+func tableExists(t *testing.T, engine *qb.Engine, name string) bool {
+	var count int
+	assert.Nil(t, engine.DB().QueryRow(
+		"SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name,
+	).Scan(&count))
+	return count == 1
+}
+
+func TestMigrator(t *testing.T) {
+	engine, m := initMigrator(t)
+	defer engine.Close()
+
+	dir, err := ioutil.TempDir("", "yago-migrate")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	up, down, err := migrate.Create(dir, 1, "Create person")
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(dir, "1_create_person.up.sql"), up)
+	assert.Equal(t, filepath.Join(dir, "1_create_person.down.sql"), down)
+	writeFile(t, up, "CREATE TABLE person (id INTEGER PRIMARY KEY, name VARCHAR(40));")
+	writeFile(t, down, "DROP TABLE person;")
+	writeFile(t, filepath.Join(dir, "3_add_email.up.sql"),
+		"ALTER TABLE person ADD COLUMN email VARCHAR(40);")
+	writeFile(t, filepath.Join(dir, "README"), "not a migration")
+
+	_, _, err = migrate.Create(dir, 1, "create_person")
+	assert.NotNil(t, err)
+
+	assert.Nil(t, m.LoadDir(dir))
+	assert.Nil(t, m.AddFunc(2, "insert_admin", func(tx *sql.Tx) error {
+		_, err := tx.Exec("INSERT INTO person (name) VALUES ('admin')")
+		return err
+	}, func(tx *sql.Tx) error {
+		_, err := tx.Exec("DELETE FROM person WHERE name = 'admin'")
+		return err
+	}))
+	assert.NotNil(t, m.AddSQL(2, "duplicate", "SELECT 1", ""))
+
+	done, err := m.UpTo(2)
+	assert.Nil(t, err)
+	assert.Len(t, done, 2)
+
+	status, err := m.Status()
+	assert.Nil(t, err)
+	if assert.Len(t, status, 3) {
+		assert.True(t, status[0].Applied)
+		assert.False(t, status[0].AppliedAt.IsZero())
+		assert.Equal(t, "insert_admin", status[1].Name)
+		assert.True(t, status[1].Applied)
+		assert.False(t, status[2].Applied)
+	}
+
+	done, err = m.Up()
+	assert.Nil(t, err)
+	if assert.Len(t, done, 1) {
+		assert.Equal(t, int64(3), done[0].Version)
+	}
+	done, err = m.Up()
+	assert.Nil(t, err)
+	assert.Len(t, done, 0)
+
+	_, err = m.Down()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), migrate.ErrNoDown.Error())
+
+	var count int
+	assert.Nil(t, engine.DB().QueryRow("SELECT COUNT(*) FROM person").Scan(&count))
+	assert.Equal(t, 1, count)
+
+	// a new migrator that ignores the last migration
+	other := migrate.New(engine)
+	assert.Nil(t, other.Add(m.Migrations()[:2]...))
+	status, err = other.Status()
+	assert.Nil(t, err)
+	if assert.Len(t, status, 3) {
+		assert.True(t, status[2].Missing)
+	}
+	_, err = other.Down()
+	assert.NotNil(t, err)
+
+	_, err = engine.DB().Exec("DELETE FROM yago_migrations WHERE version = 3")
+	assert.Nil(t, err)
+	done, err = other.DownTo(0)
+	assert.Nil(t, err)
+	assert.Len(t, done, 2)
+	assert.False(t, tableExists(t, engine, "person"))
+}
+
+func TestMigratorFailure(t *testing.T) {
+	engine, m := initMigrator(t)
+	defer engine.Close()
+
+	assert.Nil(t, m.AddSQL(1, "ok", "CREATE TABLE t1 (id INTEGER)", "DROP TABLE t1"))
+	assert.Nil(t, m.AddFunc(2, "failing", func(tx *sql.Tx) error {
+		if _, err := tx.Exec("CREATE TABLE t2 (id INTEGER)"); err != nil {
+			return err
+		}
+		return errors.New("failure")
+	}, nil))
+
+	done, err := m.Up()
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "yago migrate: Migration 2_failing failed: failure", err.Error())
+	}
+	assert.Len(t, done, 1)
+	assert.True(t, tableExists(t, engine, "t1"))
+	assert.False(t, tableExists(t, engine, "t2"))
+
+	status, err := m.Status()
+	assert.Nil(t, err)
+	assert.True(t, status[0].Applied)
+	assert.False(t, status[1].Applied)
+}
+
+func TestMigratorLock(t *testing.T) {
+	engine, m := initMigrator(t)
+	defer engine.Close()
+
+	assert.Nil(t, m.AddSQL(1, "ok", "CREATE TABLE t1 (id INTEGER)", "DROP TABLE t1"))
+	_, err := m.Status()
+	assert.Nil(t, err)
+
+	_, err = engine.DB().Exec(
+		"INSERT INTO yago_migrations_lock (id, locked_at) VALUES (1, CURRENT_TIMESTAMP)")
+	assert.Nil(t, err)
+	_, err = m.Up()
+	assert.Equal(t, migrate.ErrLocked, err)
+
+	assert.Nil(t, m.ForceUnlock())
+	done, err := m.Up()
+	assert.Nil(t, err)
+	assert.Len(t, done, 1)
+}
+
+type Contact struct {
+	ID    int64   `yago:"primary_key,auto_increment"`
+	Name  string  `yago:"index"`
+	Email *string `yago:"."`
+}
+
+func (Contact) StructType() reflect.Type {
+	return reflect.TypeOf(Contact{})
+}
+
+func TestAutogenerate(t *testing.T) {
+	engine, m := initMigrator(t)
+	defer engine.Close()
+
+	dir, err := ioutil.TempDir("", "yago-migrate")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, stmt := range []string{
+		"CREATE TABLE contact (id INTEGER PRIMARY KEY AUTOINCREMENT, name VARCHAR(255) NOT NULL)",
+		"CREATE TABLE legacy (id INTEGER PRIMARY KEY)",
+	} {
+		_, err := engine.DB().Exec(stmt)
+		assert.Nil(t, err)
+	}
+	// the bookkeeping tables are ignored
+	_, err = m.Status()
+	assert.Nil(t, err)
+
+	meta := yago.NewMetadata()
+	mapper, err := yago.ReflectMapper(&Contact{}, yago.ReflectOptions{})
+	assert.Nil(t, err)
+	meta.AddMapper(mapper)
+
+	changes, err := m.Autogenerate(meta, dir, 1, "contact email")
+	assert.Nil(t, err)
+	var kinds []schema.ChangeKind
+	for _, c := range changes {
+		kinds = append(kinds, c.Kind)
+	}
+	assert.Equal(t, []schema.ChangeKind{
+		schema.AddColumn, schema.CreateIndex, schema.DropTable,
+	}, kinds)
+
+	up, err := ioutil.ReadFile(filepath.Join(dir, "1_contact_email.up.sql"))
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(string(up),
+		"-- REVIEW (destructive): the table data is lost\n-- drop table legacy\nDROP TABLE legacy;\n"))
+
+	assert.Nil(t, m.LoadDir(dir))
+	_, err = m.Up()
+	assert.Nil(t, err)
+
+	changes, err = m.Diff(meta)
+	assert.Nil(t, err)
+	assert.Len(t, changes, 0)
+
+	changes, err = m.Autogenerate(meta, dir, 2, "nothing")
+	assert.Nil(t, err)
+	assert.Len(t, changes, 0)
+	_, err = os.Stat(filepath.Join(dir, "2_nothing.up.sql"))
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = m.Down()
+	assert.Nil(t, err)
+	changes, err = m.Diff(meta)
+	assert.Nil(t, err)
+	assert.Len(t, changes, 3)
+}
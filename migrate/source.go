@@ -0,0 +1,101 @@
+package migrate
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	migrationFileName = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+	nonAlphaNum       = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// LoadDir adds the SQL migrations of a directory. The files are named
+// "<version>_<name>.up.sql" and "<version>_<name>.down.sql", the down
+// script being optional.
+func (m *Migrator) LoadDir(dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	migrations := make(map[int64]*Migration)
+	hasUp := make(map[int64]bool)
+	var versions []int64
+	for _, file := range files {
+		sm := migrationFileName.FindStringSubmatch(file.Name())
+		if file.IsDir() || sm == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(sm[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("yago migrate: Invalid version in '%s': %s", file.Name(), err)
+		}
+		content, err := ioutil.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return err
+		}
+		mig, ok := migrations[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: sm[2]}
+			migrations[version] = mig
+			versions = append(versions, version)
+		} else if mig.Name != sm[2] {
+			return fmt.Errorf("yago migrate: Version %d has several names (%s and %s)",
+				version, mig.Name, sm[2])
+		}
+		if sm[3] == "up" {
+			hasUp[version] = true
+			mig.UpSQL = string(content)
+		} else {
+			mig.DownSQL = string(content)
+		}
+	}
+	for _, version := range versions {
+		mig := migrations[version]
+		if !hasUp[version] {
+			return fmt.Errorf("yago migrate: Migration %s has no up script", mig)
+		}
+		if err := m.Add(*mig); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Create writes empty up and down scripts for a new migration in dir, and
+// returns their paths
+func Create(dir string, version int64, name string) (up string, down string, err error) {
+	return writeMigration(dir, version, name, "", "")
+}
+
+// writeMigration writes the up and down scripts of a new migration
+func writeMigration(dir string, version int64, name string, upSQL string, downSQL string) (up string, down string, err error) {
+	name = strings.Trim(nonAlphaNum.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return "", "", fmt.Errorf("yago migrate: Invalid migration name")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", "", err
+	}
+	base := filepath.Join(dir, fmt.Sprintf("%d_%s", version, name))
+	up, down = base+".up.sql", base+".down.sql"
+	for path, content := range map[string]string{up: upSQL, down: downSQL} {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err != nil {
+			return "", "", err
+		}
+		_, err = f.WriteString(content)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return "", "", err
+		}
+	}
+	return up, down, nil
+}
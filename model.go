@@ -2,6 +2,8 @@ package yago
 
 import (
 	"encoding"
+	"fmt"
+	"strings"
 
 	"github.com/slicebit/qb"
 )
@@ -33,6 +35,11 @@ func NewMarshaledScalarField(column qb.ColumnElem) MarshaledScalarField {
 	return MarshaledScalarField{NewScalarField(column)}
 }
 
+// scalar returns the field itself
+func (f ScalarField) scalar() ScalarField {
+	return f
+}
+
 // Accept calls the underlying column 'Accept'.
 func (f ScalarField) Accept(context *qb.CompilerContext) string {
 	return f.Column.Accept(context)
@@ -83,6 +90,138 @@ func (f ScalarField) Lte(value interface{}) qb.Clause {
 	return f.Column.Lte(value)
 }
 
+// IsNull returns a IS NULL clause
+func (f ScalarField) IsNull() qb.Clause {
+	return postfixClause{f, "IS NULL"}
+}
+
+// IsNotNull returns a IS NOT NULL clause
+func (f ScalarField) IsNotNull() qb.Clause {
+	return postfixClause{f, "IS NOT NULL"}
+}
+
+// Between returns a BETWEEN clause
+func (f ScalarField) Between(low interface{}, high interface{}) qb.Clause {
+	return betweenClause{f, low, high}
+}
+
+// ILike returns a case-insensitive LIKE clause. It is ILIKE on postgres
+// and is emulated by lowering the column and the pattern on other dialects
+func (f ScalarField) ILike(pattern string) qb.Clause {
+	return likeClause{left: f, pattern: pattern, insensitive: true}
+}
+
+// StartsWith returns a LIKE clause matching the values starting with
+// prefix. The '%' and '_' characters of prefix are escaped.
+func (f ScalarField) StartsWith(prefix string) qb.Clause {
+	return likeClause{left: f, pattern: escapeLike(prefix) + "%", escaped: true}
+}
+
+// EndsWith returns a LIKE clause matching the values ending with
+// suffix. The '%' and '_' characters of suffix are escaped.
+func (f ScalarField) EndsWith(suffix string) qb.Clause {
+	return likeClause{left: f, pattern: "%" + escapeLike(suffix), escaped: true}
+}
+
+// Contains returns a LIKE clause matching the values containing
+// value. The '%' and '_' characters of value are escaped.
+func (f ScalarField) Contains(value string) qb.Clause {
+	return likeClause{left: f, pattern: "%" + escapeLike(value) + "%", escaped: true}
+}
+
+// Not returns a NOT clause
+func Not(clause qb.Clause) qb.Clause {
+	return notClause{clause}
+}
+
+// likeEscapeChar is the escape character used by StartsWith, EndsWith and
+// Contains. A backslash would need to be escaped on mysql only.
+const likeEscapeChar = "!"
+
+var likeEscaper = strings.NewReplacer(
+	likeEscapeChar, likeEscapeChar+likeEscapeChar,
+	"%", likeEscapeChar+"%",
+	"_", likeEscapeChar+"_",
+)
+
+// escapeLike escapes the LIKE special characters of a value
+func escapeLike(value string) string {
+	return likeEscaper.Replace(value)
+}
+
+// postfixClause is a '<expr> <operator>' clause
+type postfixClause struct {
+	expr     qb.Clause
+	operator string
+}
+
+// Accept compiles the clause
+func (c postfixClause) Accept(context *qb.CompilerContext) string {
+	return c.expr.Accept(context) + " " + c.operator
+}
+
+// betweenClause is a '<expr> BETWEEN <low> AND <high>' clause
+type betweenClause struct {
+	expr qb.Clause
+	low  interface{}
+	high interface{}
+}
+
+// Accept compiles the clause
+func (c betweenClause) Accept(context *qb.CompilerContext) string {
+	expr := c.expr.Accept(context)
+	low := qb.Bind(c.low).Accept(context)
+	high := qb.Bind(c.high).Accept(context)
+	return expr + " BETWEEN " + low + " AND " + high
+}
+
+// likeClause is a LIKE clause, optionally case-insensitive or with an
+// escape character
+type likeClause struct {
+	left        qb.Clause
+	pattern     string
+	insensitive bool
+	escaped     bool
+}
+
+// Accept compiles the clause
+func (c likeClause) Accept(context *qb.CompilerContext) string {
+	left := c.left.Accept(context)
+	operator := "LIKE"
+	pattern := c.pattern
+	if c.insensitive {
+		if context.Dialect.Driver() == "postgres" {
+			operator = "ILIKE"
+		} else {
+			left = "LOWER(" + left + ")"
+			pattern = strings.ToLower(pattern)
+		}
+	}
+	sql := left + " " + operator + " " + qb.Bind(pattern).Accept(context)
+	if c.escaped {
+		sql += " ESCAPE '" + likeEscapeChar + "'"
+	}
+	return sql
+}
+
+// notClause is a 'NOT (<clause>)' clause
+type notClause struct {
+	clause qb.Clause
+}
+
+// Accept compiles the clause
+func (c notClause) Accept(context *qb.CompilerContext) string {
+	return "NOT (" + c.clause.Accept(context) + ")"
+}
+
+// Err returns the error of the negated clause, if it wraps a subquery
+func (c notClause) Err() error {
+	if e, ok := c.clause.(clauseWithErr); ok {
+		return e.Err()
+	}
+	return nil
+}
+
 // marshalValue marshals the value if it implements encoding.TextMarshaler
 func (f MarshaledScalarField) marshalValue(value interface{}) interface{} {
 	tm, ok := value.(encoding.TextMarshaler)
@@ -96,14 +235,32 @@ func (f MarshaledScalarField) marshalValue(value interface{}) interface{} {
 	return value
 }
 
+// marshalText returns the text of a value, marshaled if it is a
+// TextMarshaler, for the LIKE predicates
+func (f MarshaledScalarField) marshalText(value interface{}) string {
+	switch v := f.marshalValue(value).(type) {
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
 func (f MarshaledScalarField) marshalValues(values []interface{}) []interface{} {
 	var marshaled = make([]interface{}, len(values))
-	for _, value := range values {
-		marshaled = append(marshaled, f.marshalValue(value))
+	for i, value := range values {
+		marshaled[i] = f.marshalValue(value)
 	}
 	return marshaled
 }
 
+// Between returns a BETWEEN clause
+func (f MarshaledScalarField) Between(low interface{}, high interface{}) qb.Clause {
+	return f.ScalarField.Between(f.marshalValue(low), f.marshalValue(high))
+}
+
 // NotIn returns a NOT IN clause
 func (f MarshaledScalarField) NotIn(values ...interface{}) qb.Clause {
 	return f.Column.NotIn(f.marshalValues(values)...)
@@ -143,3 +300,26 @@ func (f MarshaledScalarField) Gte(value interface{}) qb.Clause {
 func (f MarshaledScalarField) Lte(value interface{}) qb.Clause {
 	return f.Column.Lte(f.marshalValue(value))
 }
+
+// ILike returns a case-insensitive LIKE clause, the pattern being marshaled
+func (f MarshaledScalarField) ILike(pattern interface{}) qb.Clause {
+	return f.ScalarField.ILike(f.marshalText(pattern))
+}
+
+// StartsWith returns a LIKE clause matching the values starting with the
+// marshaled prefix
+func (f MarshaledScalarField) StartsWith(prefix interface{}) qb.Clause {
+	return f.ScalarField.StartsWith(f.marshalText(prefix))
+}
+
+// EndsWith returns a LIKE clause matching the values ending with the
+// marshaled suffix
+func (f MarshaledScalarField) EndsWith(suffix interface{}) qb.Clause {
+	return f.ScalarField.EndsWith(f.marshalText(suffix))
+}
+
+// Contains returns a LIKE clause matching the values containing the
+// marshaled value
+func (f MarshaledScalarField) Contains(value interface{}) qb.Clause {
+	return f.ScalarField.Contains(f.marshalText(value))
+}
@@ -0,0 +1,72 @@
+package yago_test
+
+import (
+	"testing"
+
+	"github.com/orus-io/yago"
+	"github.com/slicebit/qb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarshaledInBinds(t *testing.T) {
+	db, model, cleanup := initModel(t)
+	defer cleanup()
+
+	_, binds := asSQLBind(db.Query(model.PersonStruct).Where(
+		model.PersonStruct.Gender.In(Male, Female),
+	))
+	assert.Equal(t, []interface{}{[]byte("male"), []byte("female")}, binds)
+
+	_, binds = asSQLBind(db.Query(model.PersonStruct).Where(
+		yago.Not(model.PersonStruct.Gender.StartsWith(Female)),
+	))
+	assert.Equal(t, []interface{}{"female%"}, binds)
+}
+
+func TestPredicates(t *testing.T) {
+	db, model, cleanup := initModel(t)
+	defer cleanup()
+
+	for _, p := range []PersonStruct{
+		{FirstName: "John", Gender: Male},
+		{FirstName: "Jane", Gender: Female},
+		{FirstName: "Malcom", Gender: Male},
+		{FirstName: "50%", LastName: "Percent"},
+		{FirstName: "500"},
+	} {
+		assert.Nil(t, db.Insert(&p))
+	}
+	_, err := db.Engine.DB().Exec(
+		"UPDATE person_struct SET last_name = NULL WHERE first_name = 'Jane'")
+	assert.Nil(t, err)
+
+	m := model.PersonStruct
+	for _, tt := range []struct {
+		clause qb.Clause
+		count  int
+	}{
+		{m.LastName.IsNull(), 1},
+		{m.LastName.IsNotNull(), 4},
+		{m.FirstName.Between("J", "Jz"), 2},
+		{m.FirstName.ILike("jo%"), 1},
+		{m.FirstName.StartsWith("Ma"), 1},
+		{m.FirstName.StartsWith("50%"), 1},
+		{m.FirstName.Contains("0%"), 1},
+		{m.FirstName.Contains("_"), 0},
+		{m.FirstName.EndsWith("ne"), 1},
+		{yago.Not(m.FirstName.Eq("John")), 4},
+		{m.Gender.In(Male, Female), 3},
+		{m.Gender.NotIn(Male), 3},
+		{m.Gender.Between(Female, Female), 1},
+		{m.Gender.StartsWith(Male), 2},
+		{m.Gender.EndsWith(Male), 3},
+		{m.Gender.Contains(Female), 1},
+		{m.Gender.ILike(Female), 1},
+		{m.Gender.ILike("%MALE"), 3},
+		{yago.Not(m.Gender.Eq(Male)), 3},
+	} {
+		var count int
+		assert.Nil(t, db.Query(model.PersonStruct).Where(tt.clause).Count(&count))
+		assert.Equal(t, tt.count, count, asSQL(db.Query(model.PersonStruct).Where(tt.clause)))
+	}
+}
@@ -0,0 +1,180 @@
+package yago
+
+import (
+	"strings"
+
+	"github.com/slicebit/qb"
+)
+
+// SetSchema sets the schema of the tables. It is applied when compiling the
+// statements, the generated table names are left untouched.
+// On sqlite, the schema is the name of an attached database.
+func (m *Metadata) SetSchema(schema string) {
+	m.schema = schema
+}
+
+// Schema returns the schema of the tables
+func (m *Metadata) Schema() string {
+	return m.schema
+}
+
+// SetTablePrefix sets a prefix prepended to the table names when compiling
+// the statements, and to the index names in DDL
+func (m *Metadata) SetTablePrefix(prefix string) {
+	m.tablePrefix = prefix
+}
+
+// TablePrefix returns the table prefix
+func (m *Metadata) TablePrefix() string {
+	return m.tablePrefix
+}
+
+// addTable registers a table in the qb metadata, and its name so it is
+// qualified by the naming dialect
+func (m *Metadata) addTable(table *qb.TableElem) {
+	m.qbMeta.AddTable(*table)
+	m.tableNames[table.Name] = true
+}
+
+// namingDialect qualifies the table names with the schema and the table
+// prefix of a Metadata. Its compiler renames the tables and the columns
+// before compiling them with the dialect compiler, so the column names are
+// left untouched.
+type namingDialect struct {
+	qb.Dialect
+	metadata *Metadata
+	schema   *string
+}
+
+// newNamingDialect wraps a dialect. If schema is not nil, it overrides
+// the metadata schema.
+func newNamingDialect(dialect qb.Dialect, metadata *Metadata, schema *string) namingDialect {
+	if d, ok := dialect.(namingDialect); ok {
+		dialect = d.Dialect
+	}
+	return namingDialect{Dialect: dialect, metadata: metadata, schema: schema}
+}
+
+// currentSchema returns the schema of the tables
+func (d namingDialect) currentSchema() string {
+	if d.schema != nil {
+		return *d.schema
+	}
+	return d.metadata.schema
+}
+
+// prefixedName returns the name of a table with the table prefix, if it is
+// a table of the metadata
+func (d namingDialect) prefixedName(name string) string {
+	if !d.metadata.tableNames[name] {
+		return name
+	}
+	return d.metadata.tablePrefix + name
+}
+
+// qualifiedName returns the name of a table with the table prefix and the
+// schema, if it is a table of the metadata
+func (d namingDialect) qualifiedName(name string) string {
+	if schema := d.currentSchema(); schema != "" && d.metadata.tableNames[name] {
+		return schema + "." + d.prefixedName(name)
+	}
+	return d.prefixedName(name)
+}
+
+// Escape escapes a name. The schema and the table name of a qualifiedName
+// are escaped separately.
+func (d namingDialect) Escape(name string) string {
+	if schema := d.currentSchema(); schema != "" && strings.HasPrefix(name, schema+".") {
+		table := name[len(schema)+1:]
+		if strings.HasPrefix(table, d.metadata.tablePrefix) &&
+			d.metadata.tableNames[table[len(d.metadata.tablePrefix):]] {
+			return d.Dialect.Escape(schema) + "." + d.Dialect.Escape(table)
+		}
+	}
+	return d.Dialect.Escape(name)
+}
+
+// qualify escapes a table name with the table prefix and the schema
+func (d namingDialect) qualify(name string) string {
+	return d.Escape(d.qualifiedName(name))
+}
+
+// GetCompiler returns a compiler that qualifies the table names
+func (d namingDialect) GetCompiler() qb.Compiler {
+	return namingCompiler{Compiler: d.Dialect.GetCompiler(), dialect: d}
+}
+
+// escapeTable escapes a table name, qualified if the dialect is a naming
+// dialect
+func escapeTable(dialect qb.Dialect, name string) string {
+	if d, ok := dialect.(namingDialect); ok {
+		return d.qualify(name)
+	}
+	return dialect.Escape(name)
+}
+
+// escapeUnqualified escapes a table name with its prefix but without its
+// schema, as required by table aliases and references to the FROM tables
+func escapeUnqualified(dialect qb.Dialect, name string) string {
+	if d, ok := dialect.(namingDialect); ok {
+		return d.Dialect.Escape(d.prefixedName(name))
+	}
+	return dialect.Escape(name)
+}
+
+// namingCompiler is the compiler of a naming dialect. It renames the tables
+// with their qualified name, and the tables of the columns with their
+// prefixed name, then compiles them with the dialect compiler.
+type namingCompiler struct {
+	qb.Compiler
+	dialect namingDialect
+}
+
+// VisitTable compiles a table reference with its qualified name
+func (c namingCompiler) VisitTable(context *qb.CompilerContext, table qb.TableElem) string {
+	table.Name = c.dialect.qualifiedName(table.Name)
+	return c.Compiler.VisitTable(context, table)
+}
+
+// VisitColumn compiles a column, which table name is prefixed. qb leaves
+// the columns of the default table of the statement unqualified, this
+// table being designated by its name or by its qualified name.
+func (c namingCompiler) VisitColumn(context *qb.CompilerContext, column qb.ColumnElem) string {
+	if !context.InSubQuery && column.Table != "" &&
+		(context.DefaultTableName == column.Table ||
+			context.DefaultTableName == c.dialect.qualifiedName(column.Table)) {
+		column.Table = context.DefaultTableName
+	} else {
+		column.Table = c.dialect.prefixedName(column.Table)
+	}
+	return c.Compiler.VisitColumn(context, column)
+}
+
+// VisitInsert compiles an INSERT statement, which table name is qualified
+func (c namingCompiler) VisitInsert(context *qb.CompilerContext, insert qb.InsertStmt) string {
+	insert.Table.Name = c.dialect.qualifiedName(insert.Table.Name)
+	return c.Compiler.VisitInsert(context, insert)
+}
+
+// VisitUpdate compiles an UPDATE statement, which table name is qualified
+func (c namingCompiler) VisitUpdate(context *qb.CompilerContext, update qb.UpdateStmt) string {
+	update.Table.Name = c.dialect.qualifiedName(update.Table.Name)
+	return c.Compiler.VisitUpdate(context, update)
+}
+
+// VisitDelete compiles a DELETE statement, which table name is qualified
+func (c namingCompiler) VisitDelete(context *qb.CompilerContext, del qb.DeleteStmt) string {
+	del.Table.Name = c.dialect.qualifiedName(del.Table.Name)
+	return c.Compiler.VisitDelete(context, del)
+}
+
+// WithSchema returns a view of the DB that uses another schema. It shares
+// the connection pool, the Metadata, the Models and the callbacks of db.
+// Closing the view closes db.
+func (db *DB) WithSchema(schema string) *DB {
+	engine := *db.Engine
+	engine.SetDialect(newNamingDialect(db.Engine.Dialect(), db.Metadata, &schema))
+	view := *db
+	view.Engine = &engine
+	return &view
+}
@@ -0,0 +1,150 @@
+package yago_test
+
+import (
+	"testing"
+
+	"github.com/orus-io/yago"
+	"github.com/slicebit/qb"
+	"github.com/stretchr/testify/assert"
+)
+
+func initSqliteEngine(t *testing.T) *qb.Engine {
+	engine, err := qb.New("sqlite3", ":memory:")
+	assert.Nil(t, err)
+	// a sqlite in-memory database is bound to its connection
+	engine.DB().SetMaxOpenConns(1)
+	return engine
+}
+
+func TestTablePrefix(t *testing.T) {
+	engine := initSqliteEngine(t)
+	defer engine.Close()
+
+	meta := yago.NewMetadata()
+	meta.SetTablePrefix("acme_")
+	model := NewFixtureModel(meta)
+	dialect := engine.Dialect()
+	db := yago.New(meta, engine)
+	// the engine of the caller is left untouched
+	assert.Equal(t, dialect, engine.Dialect())
+	assert.Nil(t, meta.CreateAll(engine, false))
+
+	p := PersonStruct{FirstName: "John"}
+	assert.Nil(t, db.Insert(&p))
+
+	var count int
+	assert.Nil(t, engine.DB().QueryRow("SELECT COUNT(*) FROM acme_person_struct").Scan(&count))
+	assert.Equal(t, 1, count)
+
+	var p1 PersonStruct
+	assert.Nil(t, db.Query(model.PersonStruct).
+		Where(model.PersonStruct.FirstName.Eq("John")).One(&p1))
+	assert.Equal(t, p.ID, p1.ID)
+
+	p1.LastName = "Doe"
+	assert.Nil(t, db.Update(&p1))
+	assert.Nil(t, db.Delete(&p1))
+}
+
+func TestTablePrefixColumns(t *testing.T) {
+	engine := initSqliteEngine(t)
+	defer engine.Close()
+
+	meta := yago.NewMetadata()
+	meta.SetTablePrefix("acme_")
+	model := NewFixtureModel(meta)
+	// a table named like the SimpleStruct "name" column
+	meta.AddNamedMapper("name", yago.NewTableMapper(model.SimpleStruct.GetMapper(), "name", "name"))
+	db := yago.New(meta, engine)
+	assert.Nil(t, meta.CreateAll(engine, false))
+
+	q := db.Query(model.SimpleStruct).Where(model.SimpleStruct.Name.Eq("John"))
+	sql, _, err := q.ToSQL()
+	assert.Nil(t, err)
+	assert.Contains(t, sql, "FROM acme_simple_struct")
+	assert.NotContains(t, sql, "acme_name")
+
+	s := SimpleStruct{Name: "John"}
+	assert.Nil(t, db.Insert(&s))
+	var count int
+	assert.Nil(t, q.Count(&count))
+	assert.Equal(t, 1, count)
+
+	sql, _, err = db.Query(model.SimpleStruct.Using("name")).ToSQL()
+	assert.Nil(t, err)
+	assert.Contains(t, sql, "FROM acme_name")
+}
+
+func TestWithSchema(t *testing.T) {
+	engine := initSqliteEngine(t)
+	defer engine.Close()
+
+	meta := yago.NewMetadata()
+	model := NewFixtureModel(meta)
+	db := yago.New(meta, engine)
+
+	_, err := engine.DB().Exec("ATTACH DATABASE ':memory:' AS acme")
+	assert.Nil(t, err)
+
+	acme := db.WithSchema("acme")
+	acmeMeta := yago.NewMetadata()
+	acmeMeta.SetSchema("acme")
+	NewFixtureModel(acmeMeta)
+	assert.Nil(t, meta.CreateAll(engine, false))
+	assert.Nil(t, acmeMeta.CreateAll(engine, false))
+
+	sql, _, err := acme.Query(model.SimpleStruct).ToSQL()
+	assert.Nil(t, err)
+	assert.Contains(t, sql, "FROM acme.simple_struct")
+
+	assert.Nil(t, acme.Insert(&SimpleStruct{Name: "acme"}))
+	tx, err := acme.Begin()
+	assert.Nil(t, err)
+	assert.Nil(t, tx.Insert(&SimpleStruct{Name: "acme2"}))
+	assert.Nil(t, tx.Commit())
+
+	var count int
+	assert.Nil(t, engine.DB().QueryRow("SELECT COUNT(*) FROM acme.simple_struct").Scan(&count))
+	assert.Equal(t, 2, count)
+	assert.Nil(t, acme.Query(model.SimpleStruct).Count(&count))
+	assert.Equal(t, 2, count)
+	assert.Nil(t, db.Query(model.SimpleStruct).Count(&count))
+	assert.Equal(t, 0, count)
+}
+
+func TestTablePrefixSubQuery(t *testing.T) {
+	engine := initSqliteEngine(t)
+	defer engine.Close()
+
+	meta := yago.NewMetadata()
+	meta.SetTablePrefix("acme_")
+	model := NewFixtureModel(meta)
+	// a table which name is a prefix of the person_struct one
+	meta.AddNamedMapper("person", yago.NewTableMapper(model.PersonStruct.GetMapper(), "person", "person"))
+	db := yago.New(meta, engine)
+	assert.Nil(t, meta.CreateAll(engine, false))
+
+	john := PersonStruct{FirstName: "John"}
+	assert.Nil(t, db.Insert(&john))
+	assert.Nil(t, db.Insert(&PersonStruct{FirstName: "Jane"}))
+	assert.Nil(t, db.Insert(&AutoIncChild{Name: "Junior", Person: john.ID}))
+
+	correlated := db.Query(model.AutoIncChild).Select(qb.SQLText("1")).Where(
+		model.AutoIncChild.Person.Column.Eq(model.PersonStruct.ID.Column),
+	)
+	q := db.Query(model.PersonStruct).Where(yago.Exists(correlated))
+	sql, _, err := q.ToSQL()
+	assert.Nil(t, err)
+	assert.Contains(t, sql, "FROM acme_person_struct")
+	assert.Contains(t, sql, "FROM acme_auto_inc_child")
+	assert.Contains(t, sql, "acme_person_struct.id")
+	assert.NotContains(t, sql, "acme_acme_")
+
+	var p PersonStruct
+	assert.Nil(t, q.One(&p))
+	assert.Equal(t, "John", p.FirstName)
+
+	sql, _, err = db.Query(model.PersonStruct.Using("person")).ToSQL()
+	assert.Nil(t, err)
+	assert.Regexp(t, `FROM acme_person($|\s)`, sql)
+}
@@ -0,0 +1,129 @@
+package yago
+
+import (
+	"fmt"
+
+	"github.com/slicebit/qb"
+)
+
+// OrderTerm is a ORDER BY term: an expression, a direction and an optional
+// NULLS FIRST/LAST ordering
+type OrderTerm struct {
+	expr  qb.Clause
+	desc  bool
+	nulls string
+}
+
+// Asc returns a ascending ORDER BY term on any expression
+func Asc(expr qb.Clause) OrderTerm {
+	return OrderTerm{expr: expr}
+}
+
+// Desc returns a descending ORDER BY term on any expression
+func Desc(expr qb.Clause) OrderTerm {
+	return OrderTerm{expr: expr, desc: true}
+}
+
+// NullsFirst puts the NULL values first
+func (t OrderTerm) NullsFirst() OrderTerm {
+	t.nulls = "FIRST"
+	return t
+}
+
+// NullsLast puts the NULL values last
+func (t OrderTerm) NullsLast() OrderTerm {
+	t.nulls = "LAST"
+	return t
+}
+
+// Accept compiles the term
+func (t OrderTerm) Accept(context *qb.CompilerContext) string {
+	return t.compile(context, false)
+}
+
+// column returns the column if the term expression is a column
+func (t OrderTerm) column() (qb.ColumnElem, bool) {
+	switch c := t.expr.(type) {
+	case qb.ColumnElem:
+		return c, true
+	case ScalarField:
+		return c.Column, true
+	case MarshaledScalarField:
+		return c.Column, true
+	}
+	return qb.ColumnElem{}, false
+}
+
+// columnName returns the column name if the term expression is a column
+func (t OrderTerm) columnName() (string, bool) {
+	column, ok := t.column()
+	return column.Name, ok
+}
+
+// compile compiles the term. If byName is true, a column is designated by
+// its sole name, as required when ordering a compound statement.
+// NULLS FIRST/LAST is native on postgres, and emulated on other dialects
+// with a 'expr IS NULL' term, a compound statement being then wrapped in a
+// derived table by selectQuery.
+func (t OrderTerm) compile(context *qb.CompilerContext, byName bool) string {
+	expr := func() string {
+		if byName {
+			if name, ok := t.columnName(); ok {
+				return context.Dialect.Escape(name)
+			}
+		}
+		return t.expr.Accept(context)
+	}
+	direction := "ASC"
+	if t.desc {
+		direction = "DESC"
+	}
+	if t.nulls == "" {
+		return expr() + " " + direction
+	}
+	if context.Dialect.Driver() == "postgres" {
+		return expr() + " " + direction + " NULLS " + t.nulls
+	}
+	nullsDirection := "ASC"
+	if t.nulls == "FIRST" {
+		nullsDirection = "DESC"
+	}
+	// expr() must be called twice so the binds are added twice
+	isNull := expr() + " IS NULL " + nullsDirection
+	return isNull + ", " + expr() + " " + direction
+}
+
+// makeOrderTerm converts a OrderBy argument to a OrderTerm
+func makeOrderTerm(clause qb.Clause) (OrderTerm, error) {
+	switch c := clause.(type) {
+	case nil:
+		return OrderTerm{}, fmt.Errorf("yago Query.OrderBy(): Got a nil clause")
+	case OrderTerm:
+		return c, nil
+	case DerivedTable:
+		return OrderTerm{}, fmt.Errorf(
+			"yago Query.OrderBy(): Cannot order by a %T", clause)
+	default:
+		return OrderTerm{expr: clause}, nil
+	}
+}
+
+// Asc returns a ascending ORDER BY term
+func (f ScalarField) Asc() OrderTerm {
+	return Asc(f)
+}
+
+// Desc returns a descending ORDER BY term
+func (f ScalarField) Desc() OrderTerm {
+	return Desc(f)
+}
+
+// NullsFirst returns a ascending ORDER BY term with the NULL values first
+func (f ScalarField) NullsFirst() OrderTerm {
+	return Asc(f).NullsFirst()
+}
+
+// NullsLast returns a ascending ORDER BY term with the NULL values last
+func (f ScalarField) NullsLast() OrderTerm {
+	return Asc(f).NullsLast()
+}
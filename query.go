@@ -12,7 +12,17 @@ import (
 type Query struct {
 	db         IDB
 	mapper     Mapper
+	model      MapperProvider
 	selectStmt qb.SelectStmt
+	compound   *compoundStmt
+	orderBy    []OrderTerm
+	offset     int
+	limit      *int
+	lock       *lockClause
+	joins      []Mapper
+	related    []string
+	noCache    bool
+	err        error
 }
 
 // NewQuery creates a new query
@@ -24,98 +34,401 @@ func NewQuery(db IDB, mapper Mapper) Query {
 	}
 }
 
-// SelectStmt returns the builded SelectStmt
+// newModelQuery creates a new query on the mapper of a model. The model
+// fields can then be designated by name, see FilterBy.
+func newModelQuery(db IDB, mp MapperProvider) Query {
+	q := NewQuery(db, mp.GetMapper())
+	q.model = mp
+	return q
+}
+
+// SelectStmt returns the builded SelectStmt, with its ORDER BY, LIMIT,
+// OFFSET and FOR UPDATE clauses.
+// It panics if the query has clauses that a qb.SelectStmt cannot express:
+// a compound query, ORDER BY terms that are not columns, have different
+// directions or a NULLS ordering, FOR SHARE, NOWAIT or SKIP LOCKED. Use
+// Statement to get the statement of any query.
 func (q Query) SelectStmt() qb.SelectStmt {
+	stmt, err := q.qbSelectStmt()
+	if err != nil {
+		panic(err)
+	}
+	return stmt
+}
+
+// BaseSelectStmt returns the builded SelectStmt, without the ORDER BY,
+// LIMIT, OFFSET and FOR UPDATE/SHARE clauses of the query
+func (q Query) BaseSelectStmt() qb.SelectStmt {
 	return q.selectStmt
 }
 
+// qbSelectStmt returns the select statement with the clauses of the query,
+// or an error if qb cannot express them
+func (q Query) qbSelectStmt() (qb.SelectStmt, error) {
+	stmt := q.selectStmt
+	if q.compound != nil {
+		return stmt, fmt.Errorf(
+			"yago Query.SelectStmt(): A compound query is not a qb.SelectStmt, use Statement")
+	}
+	if len(q.orderBy) != 0 {
+		var columns []qb.ColumnElem
+		for _, term := range q.orderBy {
+			column, ok := term.column()
+			if !ok || term.nulls != "" || term.desc != q.orderBy[0].desc {
+				return stmt, fmt.Errorf(
+					"yago Query.SelectStmt(): qb cannot express the ORDER BY terms, use Statement")
+			}
+			columns = append(columns, column)
+		}
+		stmt = stmt.OrderBy(columns...)
+		if q.orderBy[0].desc {
+			stmt = stmt.Desc()
+		}
+	}
+	if q.limit != nil {
+		stmt = stmt.Limit(q.offset, *q.limit)
+	}
+	if q.lock != nil {
+		if q.lock.share || q.lock.wait != "" {
+			return stmt, fmt.Errorf(
+				"yago Query.SelectStmt(): qb cannot express FOR SHARE, NOWAIT or SKIP LOCKED, use Statement")
+		}
+		stmt = stmt.ForUpdate(q.lock.tables...)
+	}
+	return stmt, nil
+}
+
+// Statement returns the complete statement run by the query
+func (q Query) Statement() qb.Builder {
+	return q.statement()
+}
+
 // Select redefines the SELECT clauses
 func (q Query) Select(clause ...qb.Clause) Query {
+	if q.compound != nil {
+		return q.setErr(compoundErr("Select"))
+	}
 	q.selectStmt = q.selectStmt.Select(clause...)
 	return q
 }
 
+// Err returns the first error that occured while building the query, if any.
+// The same error is returned when the query is executed.
+func (q Query) Err() error {
+	return q.err
+}
+
 // Where set the filter clause of the query
 func (q Query) Where(clauses ...qb.Clause) Query {
-	return Query{
-		db:         q.db,
-		mapper:     q.mapper,
-		selectStmt: q.selectStmt.Where(clauses...),
+	if q.compound != nil {
+		return q.setErr(compoundErr("Where"))
 	}
+	q.selectStmt = q.selectStmt.Where(clauses...)
+	return q.checkClauses(clauses)
 }
 
 // Filter combines the given clauses with the current Where clause of the Query
 func (q Query) Filter(clauses ...qb.Clause) Query {
+	if q.compound != nil {
+		return q.setErr(compoundErr("Filter"))
+	}
 	if q.selectStmt.WhereClause == nil {
 		q.selectStmt = q.selectStmt.Where(clauses...)
 	} else {
 		where := q.selectStmt.WhereClause.And(clauses...)
 		q.selectStmt.WhereClause = &where
 	}
+	return q.checkClauses(clauses)
+}
+
+// checkClauses records the errors, and the tables, of the subqueries used
+// in clauses
+func (q Query) checkClauses(clauses []qb.Clause) Query {
+	for _, clause := range clauses {
+		if c, ok := clause.(clauseWithErr); ok && c.Err() != nil {
+			q = q.setErr(c.Err())
+		}
+		if c, ok := clause.(clauseWithTables); ok {
+			q.related = append(q.related[:len(q.related):len(q.related)], c.readTables()...)
+		}
+	}
+	return q
+}
+
+// addJoin records a joined mapper without sharing the joins slice with
+// the query it derives from
+func (q Query) addJoin(mapper Mapper) Query {
+	q.joins = append(q.joins[:len(q.joins):len(q.joins)], mapper)
 	return q
 }
 
 // InnerJoin joins a table
 func (q Query) InnerJoin(mp MapperProvider, clause ...qb.Clause) Query {
+	if q.compound != nil {
+		return q.setErr(compoundErr("InnerJoin"))
+	}
 	q.selectStmt = q.selectStmt.InnerJoin(mp.GetMapper().Table(), clause...)
-	return q
+	return q.addJoin(mp.GetMapper())
 }
 
 // LeftJoin joins a table
 func (q Query) LeftJoin(mp MapperProvider, clause ...qb.Clause) Query {
+	if q.compound != nil {
+		return q.setErr(compoundErr("LeftJoin"))
+	}
 	q.selectStmt = q.selectStmt.LeftJoin(mp.GetMapper().Table(), clause...)
-	return q
+	return q.addJoin(mp.GetMapper())
 }
 
 // RightJoin joins a table
 func (q Query) RightJoin(mp MapperProvider, clause ...qb.Clause) Query {
+	if q.compound != nil {
+		return q.setErr(compoundErr("RightJoin"))
+	}
 	q.selectStmt = q.selectStmt.RightJoin(mp.GetMapper().Table(), clause...)
+	return q.addJoin(mp.GetMapper())
+}
+
+// JoinQuery joins a derived table with an INNER JOIN
+func (q Query) JoinQuery(t DerivedTable, clause ...qb.Clause) Query {
+	if q.compound != nil {
+		return q.setErr(compoundErr("JoinQuery"))
+	}
+	q.selectStmt = q.selectStmt.InnerJoin(t, clause...)
+	return q.checkClauses([]qb.Clause{t})
+}
+
+// LeftJoinQuery joins a derived table with a LEFT JOIN
+func (q Query) LeftJoinQuery(t DerivedTable, clause ...qb.Clause) Query {
+	if q.compound != nil {
+		return q.setErr(compoundErr("LeftJoinQuery"))
+	}
+	q.selectStmt = q.selectStmt.LeftJoin(t, clause...)
+	return q.checkClauses([]qb.Clause{t})
+}
+
+// JoinOn joins a table with an INNER JOIN. The ON clause is inferred from
+// the foreign keys linking the joined table to the tables already present
+// in the query.
+func (q Query) JoinOn(mp MapperProvider) Query {
+	on, err := q.inferJoinClause(mp.GetMapper())
+	if err != nil {
+		return q.setErr(err)
+	}
+	return q.InnerJoin(mp, on)
+}
+
+// LeftJoinOn joins a table with a LEFT JOIN. The ON clause is inferred the
+// same way JoinOn does.
+func (q Query) LeftJoinOn(mp MapperProvider) Query {
+	on, err := q.inferJoinClause(mp.GetMapper())
+	if err != nil {
+		return q.setErr(err)
+	}
+	return q.LeftJoin(mp, on)
+}
+
+// RightJoinOn joins a table with a RIGHT JOIN. The ON clause is inferred the
+// same way JoinOn does.
+func (q Query) RightJoinOn(mp MapperProvider) Query {
+	on, err := q.inferJoinClause(mp.GetMapper())
+	if err != nil {
+		return q.setErr(err)
+	}
+	return q.RightJoin(mp, on)
+}
+
+// setErr records err unless an error was already recorded
+func (q Query) setErr(err error) Query {
+	if q.err == nil {
+		q.err = err
+	}
 	return q
 }
 
-// OrderBy add a ORDER BY clause
+// inferJoinClause looks for a single foreign key between the mapper table
+// and the tables of the query, and returns the matching ON clause
+func (q Query) inferJoinClause(mapper Mapper) (qb.Clause, error) {
+	joined := mapper.Table()
+	var candidates []qb.Clause
+	for _, m := range append([]Mapper{q.mapper}, q.joins...) {
+		table := m.Table()
+		if table.Name == joined.Name {
+			continue
+		}
+		candidates = append(candidates, foreignKeyClauses(joined, table)...)
+		candidates = append(candidates, foreignKeyClauses(table, joined)...)
+	}
+	switch len(candidates) {
+	case 0:
+		return nil, fmt.Errorf(
+			"yago Query.JoinOn(): No foreign key between '%s' and the query tables",
+			joined.Name)
+	case 1:
+		return candidates[0], nil
+	default:
+		return nil, fmt.Errorf(
+			"yago Query.JoinOn(): Ambiguous join, %d foreign keys link '%s' to the query tables",
+			len(candidates), joined.Name)
+	}
+}
+
+// foreignKeyClauses returns a clause for each foreign key of 'from' that
+// references 'to'
+func foreignKeyClauses(from *qb.TableElem, to *qb.TableElem) []qb.Clause {
+	var clauses []qb.Clause
+	for _, fk := range from.ForeignKeyConstraints.FKeys {
+		if fk.RefTable != to.Name {
+			continue
+		}
+		var conditions []qb.Clause
+		for i, col := range fk.Cols {
+			conditions = append(conditions, from.C(col).Eq(to.C(fk.RefCols[i])))
+		}
+		if len(conditions) == 1 {
+			clauses = append(clauses, conditions[0])
+		} else {
+			clauses = append(clauses, qb.And(conditions...))
+		}
+	}
+	return clauses
+}
+
+// OrderBy set the ORDER BY clause. It accepts fields, columns, any
+// expression, or OrderTerm (see ScalarField.Asc and ScalarField.Desc).
+// On a compound query, only the columns of the select list can be used.
 func (q Query) OrderBy(clauses ...qb.Clause) Query {
-	// Right now qb.selectStmt.OrderBy only accepts ColumnElem
-	var columns []qb.ColumnElem
+	var terms []OrderTerm
 	for _, clause := range clauses {
-		scalf, ok := clause.(ScalarField)
-		if ok {
-			columns = append(columns, scalf.Column)
-			continue
+		term, err := makeOrderTerm(clause)
+		if err != nil {
+			return q.setErr(err)
 		}
-		col, ok := clause.(qb.ColumnElem)
-		if !ok {
-			panic("OrderBy only accepts ScalarField and qb.ColumnElem arguments")
+		if _, ok := term.columnName(); q.compound != nil && !ok {
+			return q.setErr(fmt.Errorf(
+				"yago Query.OrderBy(): A compound query can only be ordered by columns"))
 		}
-		columns = append(columns, col)
+		terms = append(terms, term)
 	}
-	q.selectStmt = q.selectStmt.OrderBy(columns...)
+	q.orderBy = terms
 	return q
 }
 
-// ForUpdate add a FOR UPDATE clause
+// Limit set the OFFSET and LIMIT clauses
+func (q Query) Limit(offset int, count int) Query {
+	q.offset = offset
+	q.limit = &count
+	return q
+}
+
+// ForUpdate add a FOR UPDATE clause. On postgres, the locked tables can
+// be restricted to the given mappers ones.
+// Sqlite has no row locks, the clause is omitted.
 func (q Query) ForUpdate(mps ...MapperProvider) Query {
-	var tables []qb.TableElem
+	return q.setLock("ForUpdate", false, mps)
+}
+
+// ForShare add a FOR SHARE clause, see ForUpdate
+func (q Query) ForShare(mps ...MapperProvider) Query {
+	return q.setLock("ForShare", true, mps)
+}
+
+func (q Query) setLock(method string, share bool, mps []MapperProvider) Query {
+	if q.compound != nil {
+		return q.setErr(compoundErr(method))
+	}
+	lock := lockClause{share: share}
 	for _, mp := range mps {
-		tables = append(tables, *mp.GetMapper().Table())
+		lock.tables = append(lock.tables, *mp.GetMapper().Table())
 	}
-	q.selectStmt = q.selectStmt.ForUpdate(tables...)
+	q.lock = &lock
 	return q
 }
 
+// NoWait makes the FOR UPDATE or FOR SHARE clause fail instead of waiting
+// for the locked rows.
+// The query fails on sqlite, which cannot honor it.
+func (q Query) NoWait() Query {
+	return q.setLockWait("NoWait", "NOWAIT")
+}
+
+// SkipLocked makes the FOR UPDATE or FOR SHARE clause skip the locked rows.
+// The query fails on sqlite, which cannot honor it.
+func (q Query) SkipLocked() Query {
+	return q.setLockWait("SkipLocked", "SKIP LOCKED")
+}
+
+func (q Query) setLockWait(method string, wait string) Query {
+	if q.lock == nil {
+		return q.setErr(fmt.Errorf(
+			"yago Query.%s(): Requires ForUpdate or ForShare", method))
+	}
+	lock := *q.lock
+	lock.wait = wait
+	q.lock = &lock
+	return q
+}
+
+// GetForUpdate returns a record from its primary key values, and locks it
+// with a FOR UPDATE clause. The NoWait and SkipLocked options of the query
+// are kept.
+func (q Query) GetForUpdate(s MappedStruct, pkey ...interface{}) error {
+	if q.lock == nil {
+		q = q.ForUpdate()
+	} else {
+		lock := *q.lock
+		lock.share = false
+		q.lock = &lock
+	}
+	return q.Get(s, pkey...)
+}
+
+// statement returns the statement to run
+func (q Query) statement() qb.Builder {
+	stmt := selectQuery{
+		body:    q.selectStmt,
+		orderBy: q.orderBy,
+		offset:  q.offset,
+		limit:   q.limit,
+		lock:    q.lock,
+	}
+	if q.compound != nil {
+		stmt.body = *q.compound
+		stmt.compound = true
+	}
+	return stmt
+}
+
 // SQLQuery runs the query
 func (q Query) SQLQuery() (*sql.Rows, error) {
-	return q.db.GetEngine().Query(q.selectStmt)
+	if q.err != nil {
+		return nil, q.err
+	}
+	if err := q.lock.check(dialectOf(q.db)); err != nil {
+		return nil, err
+	}
+	return q.db.GetEngine().Query(q.statement())
 }
 
-// SQLQueryRow runs the query and expects at most one row in the result
+// SQLQueryRow runs the query and expects at most one row in the result.
+// As a qb.Row cannot hold an error, it panics if the query cannot be built
+// (see Err) or run on the dialect. Scalar returns these errors instead.
 func (q Query) SQLQueryRow() qb.Row {
-	return q.db.GetEngine().QueryRow(q.selectStmt)
+	if q.err != nil {
+		panic(q.err)
+	}
+	if err := q.lock.check(dialectOf(q.db)); err != nil {
+		panic(err)
+	}
+	return q.db.GetEngine().QueryRow(q.statement())
 }
 
 // One returns one and only one struct from the query.
 // If query has no result or more than one, an error is returned
 func (q Query) One(s MappedStruct) error {
+	if cache := q.getCache(); cache != nil {
+		return cache.load(q, "One", s, func() error { return q.NoCache().One(s) })
+	}
 	rows, err := q.SQLQuery()
 	if err != nil {
 		return err
@@ -143,6 +456,9 @@ func (q Query) Get(s MappedStruct, pkey ...interface{}) error {
 
 // All load all the structs matching the query
 func (q Query) All(value interface{}) error {
+	if cache := q.getCache(); cache != nil {
+		return cache.load(q, "All", value, func() error { return q.NoCache().All(value) })
+	}
 	rows, err := q.SQLQuery()
 	if err != nil {
 		return err
@@ -219,12 +535,21 @@ func (q Query) Scalar(value interface{}) error {
 // Count change the columns to COUNT(*), execute the query and returns
 // the result
 func (q Query) Count(count interface{}) error {
+	if cache := q.getCache(); cache != nil {
+		return cache.load(q, "Count", count, func() error { return q.NoCache().Count(count) })
+	}
+	if q.compound != nil {
+		return q.wrap(
+			qb.Select(qb.Count(qb.SQLText("*"))).From(q.As("compound")),
+		).Scalar(count)
+	}
 
 	// XXX mapper should be able to return a list of pkey fields
 	// XXX When qb supports COUNT(*), use it
 	q.selectStmt = q.selectStmt.Select(qb.Count(
 		q.mapper.Table().PrimaryCols()[0]),
 	)
+	q.orderBy = nil
 	return q.Select(
 		qb.Count(qb.SQLText("*")),
 	).Scalar(count)
@@ -232,9 +557,24 @@ func (q Query) Count(count interface{}) error {
 
 // Exists return true if any record matches the current query
 func (q Query) Exists() (exists bool, err error) {
-	q.selectStmt = qb.Select(qb.Exists(
+	if q.compound != nil {
+		err = q.wrap(qb.Select(Exists(q))).Scalar(&exists)
+		return
+	}
+	err = q.wrap(qb.Select(qb.Exists(
 		q.selectStmt.Select(qb.SQLText("1")).Limit(0, 1),
-	))
-	err = q.Scalar(&exists)
+	))).Scalar(&exists)
 	return
 }
+
+// wrap returns a query running stmt, which is built from q
+func (q Query) wrap(stmt qb.SelectStmt) Query {
+	return Query{
+		db:         q.db,
+		mapper:     q.mapper,
+		selectStmt: stmt,
+		related:    q.readTables(),
+		noCache:    q.noCache,
+		err:        q.err,
+	}
+}
@@ -32,6 +32,39 @@ func makeQuerySQLTests(db *yago.DB, model FixtureModel) []querySQLTests {
 			db.Query(model.PersonStruct).Select(qb.SQLText("X")).
 				OrderBy(model.PersonStruct.LastName),
 		},
+		querySQLTests{
+			"SELECT X\nFROM person_struct\nORDER BY last_name DESC, first_name ASC",
+			db.Query(model.PersonStruct).Select(qb.SQLText("X")).
+				OrderBy(model.PersonStruct.LastName.Desc(), model.PersonStruct.FirstName.Asc()),
+		},
+		querySQLTests{
+			"SELECT X\nFROM person_struct\nORDER BY last_name IS NULL DESC, last_name ASC",
+			db.Query(model.PersonStruct).Select(qb.SQLText("X")).
+				OrderBy(model.PersonStruct.LastName.NullsFirst()),
+		},
+		querySQLTests{
+			"SELECT X\nFROM person_struct\nORDER BY last_name IS NULL ASC, last_name DESC",
+			db.Query(model.PersonStruct).Select(qb.SQLText("X")).
+				OrderBy(model.PersonStruct.LastName.Desc().NullsLast()),
+		},
+		querySQLTests{
+			"SELECT X\nFROM person_struct\nORDER BY length(first_name) DESC\nLIMIT 10 OFFSET 5",
+			db.Query(model.PersonStruct).Select(qb.SQLText("X")).
+				OrderBy(yago.Desc(qb.SQLText("length(first_name)"))).
+				Limit(5, 10),
+		},
+		querySQLTests{
+			"SELECT X\nFROM person_struct\nFOR UPDATE",
+			db.Query(model.PersonStruct).Select(qb.SQLText("X")).ForUpdate(),
+		},
+		querySQLTests{
+			"SELECT X\nFROM person_struct\nFOR UPDATE SKIP LOCKED",
+			db.Query(model.PersonStruct).Select(qb.SQLText("X")).ForUpdate().SkipLocked(),
+		},
+		querySQLTests{
+			"SELECT X\nFROM person_struct\nFOR SHARE NOWAIT",
+			db.Query(model.PersonStruct).Select(qb.SQLText("X")).ForShare().NoWait(),
+		},
 	}
 }
 
@@ -59,3 +92,138 @@ func TestQuerySQL(t *testing.T) {
 		)
 	}
 }
+
+func TestJoinOn(t *testing.T) {
+	db, model, cleanup := initModel(t)
+	defer cleanup()
+
+	q := db.Query(model.PersonStruct).Select(qb.SQLText("X"))
+
+	assert.Equal(t,
+		asSQL(q.InnerJoin(
+			model.AutoIncChild,
+			model.AutoIncChild.Person.Column.Eq(model.PersonStruct.ID.Column),
+		)),
+		asSQL(q.JoinOn(model.AutoIncChild)),
+	)
+	assert.Equal(t,
+		asSQL(q.LeftJoin(
+			model.AutoIncChild,
+			model.AutoIncChild.Person.Column.Eq(model.PersonStruct.ID.Column),
+		)),
+		asSQL(q.LeftJoinOn(model.AutoIncChild)),
+	)
+	assert.Nil(t, q.JoinOn(model.AutoIncChild).Err())
+
+	// The FK can be followed from the referencing table too
+	assert.Nil(t, db.Query(model.AutoIncChild).JoinOn(model.PersonStruct).Err())
+
+	// No FK between the tables
+	noFK := q.JoinOn(model.SimpleStruct)
+	assert.NotNil(t, noFK.Err())
+	var p PersonStruct
+	assert.Equal(t, noFK.Err(), noFK.One(&p))
+}
+
+func TestOrderBy(t *testing.T) {
+	db, model, cleanup := initModel(t)
+	defer cleanup()
+
+	for _, name := range []string{"John", "Jane", "Malcom"} {
+		assert.Nil(t, db.Insert(&PersonStruct{FirstName: name}))
+	}
+
+	var persons []PersonStruct
+	assert.Nil(t, db.Query(model.PersonStruct).
+		OrderBy(model.PersonStruct.FirstName.Desc()).
+		All(&persons))
+	assert.Equal(t, "Malcom", persons[0].FirstName)
+	assert.Equal(t, "John", persons[1].FirstName)
+	assert.Equal(t, "Jane", persons[2].FirstName)
+
+	assert.NotPanics(t, func() {
+		q := db.Query(model.PersonStruct).OrderBy(nil)
+		assert.NotNil(t, q.Err())
+	})
+}
+
+func TestSelectStmt(t *testing.T) {
+	db, model, cleanup := initModel(t)
+	defer cleanup()
+
+	q := db.Query(model.PersonStruct).
+		OrderBy(model.PersonStruct.FirstName.Desc(), model.PersonStruct.LastName.Desc()).
+		Limit(5, 10).
+		ForUpdate(model.PersonStruct)
+	stmt := q.SelectStmt()
+	assert.NotNil(t, stmt.OrderByClause)
+	if assert.NotNil(t, stmt.LimitValue) && assert.NotNil(t, stmt.OffsetValue) {
+		assert.Equal(t, 10, *stmt.LimitValue)
+		assert.Equal(t, 5, *stmt.OffsetValue)
+	}
+	assert.NotNil(t, stmt.ForUpdateClause)
+
+	stmt = q.BaseSelectStmt()
+	assert.Nil(t, stmt.OrderByClause)
+	assert.Nil(t, stmt.LimitValue)
+	assert.Nil(t, stmt.ForUpdateClause)
+
+	// the clauses qb cannot express are not silently dropped
+	for _, q := range []yago.Query{
+		db.Query(model.PersonStruct).OrderBy(model.PersonStruct.FirstName.Desc(), model.PersonStruct.LastName),
+		db.Query(model.PersonStruct).OrderBy(model.PersonStruct.LastName.NullsLast()),
+		db.Query(model.PersonStruct).OrderBy(yago.Asc(qb.SQLText("length(first_name)"))),
+		db.Query(model.PersonStruct).ForShare(),
+		db.Query(model.PersonStruct).ForUpdate().NoWait(),
+		db.Query(model.PersonStruct).Union(db.Query(model.PersonStruct)),
+	} {
+		assert.Panics(t, func() { q.SelectStmt() })
+	}
+}
+
+func TestLock(t *testing.T) {
+	db, model, cleanup := initModel(t)
+	defer cleanup()
+
+	p := PersonStruct{FirstName: "John"}
+	assert.Nil(t, db.Insert(&p))
+
+	// sqlite has no row locks, FOR UPDATE and FOR SHARE are omitted
+	var p1 PersonStruct
+	assert.Nil(t, db.Query(model.PersonStruct).GetForUpdate(&p1, p.ID))
+	assert.Equal(t, "John", p1.FirstName)
+	assert.Nil(t, db.Query(model.PersonStruct).ForShare().One(&p1))
+
+	// but NOWAIT and SKIP LOCKED cannot be honored
+	err := db.Query(model.PersonStruct).ForUpdate().SkipLocked().One(&p1)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "yago Query: SKIP LOCKED is not supported by sqlite3", err.Error())
+	}
+	err = db.Query(model.PersonStruct).ForShare().NoWait().GetForUpdate(&p1, p.ID)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "yago Query: NOWAIT is not supported by sqlite3", err.Error())
+	}
+
+	err = db.Query(model.PersonStruct).NoWait().Err()
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "yago Query.NoWait(): Requires ForUpdate or ForShare", err.Error())
+	}
+}
+
+func TestSQLQueryRow(t *testing.T) {
+	db, model, cleanup := initModel(t)
+	defer cleanup()
+
+	assert.Nil(t, db.Insert(&PersonStruct{FirstName: "John"}))
+
+	var name string
+	q := db.Query(model.PersonStruct).Select(model.PersonStruct.FirstName)
+	assert.Nil(t, q.SQLQueryRow().Scan(&name))
+	assert.Equal(t, "John", name)
+
+	// the query errors are not ignored
+	assert.Panics(t, func() { q.OrderBy(nil).SQLQueryRow() })
+	assert.PanicsWithError(t, "yago Query: NOWAIT is not supported by sqlite3", func() {
+		q.ForUpdate().NoWait().SQLQueryRow()
+	})
+}
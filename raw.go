@@ -0,0 +1,78 @@
+package yago
+
+import (
+	"regexp"
+	"strconv"
+
+	"github.com/slicebit/qb"
+)
+
+// postgresPlaceholder matches the $N placeholders of a postgres raw SQL
+var postgresPlaceholder = regexp.MustCompile(`\$(\d+)`)
+
+// rawTable is a raw SQL select used as the FROM clause of a Query. It is
+// aliased with the mapper table name, so the mapper columns and the model
+// fields designate the raw select columns.
+type rawTable struct {
+	table *qb.TableElem
+	sql   string
+	args  []interface{}
+}
+
+// Accept compiles the raw select. Its arguments are added to the binds
+// as-is, so the placeholders must match the dialect ones.
+// On postgres, the $N placeholders are compiled as binds of their argument,
+// so they are numbered with the other binds of the statement.
+func (t rawTable) Accept(context *qb.CompilerContext) string {
+	sql := t.sql
+	if context.Dialect.Driver() == "postgres" {
+		sql = postgresPlaceholder.ReplaceAllStringFunc(sql, func(placeholder string) string {
+			n, err := strconv.Atoi(placeholder[1:])
+			if err != nil || n < 1 || n > len(t.args) {
+				return placeholder
+			}
+			return qb.Bind(t.args[n-1]).Accept(context)
+		})
+	} else {
+		context.Binds = append(context.Binds, t.args...)
+	}
+	return "(" + sql + ") AS " + escapeUnqualified(context.Dialect, t.table.Name)
+}
+
+// All returns the mapper table columns
+func (t rawTable) All() []qb.Clause {
+	return t.table.All()
+}
+
+// ColumnList returns the mapper table columns
+func (t rawTable) ColumnList() []qb.ColumnElem {
+	return t.table.ColumnList()
+}
+
+// C returns a mapper table column
+func (t rawTable) C(name string) qb.ColumnElem {
+	return t.table.C(name)
+}
+
+// DefaultName returns the mapper table name
+func (t rawTable) DefaultName() string {
+	return t.table.Name
+}
+
+// NewRawQuery returns a Query that selects the mapper fields from a raw
+// SQL select.
+// The raw select columns are matched by name, so their order does not
+// matter and extra columns are ignored. The placeholders of the raw SQL are
+// the first binds of the query, and must follow the dialect syntax. On
+// postgres, $N designates the Nth argument, and the binds added by Where or
+// the joins are numbered after them.
+// The returned Query can be filtered, ordered, counted...
+func NewRawQuery(db IDB, mapper Mapper, sql string, args ...interface{}) Query {
+	q := NewQuery(db, mapper)
+	q.selectStmt = qb.Select(mapper.FieldList()...).From(rawTable{
+		table: mapper.Table(),
+		sql:   sql,
+		args:  args,
+	})
+	return q
+}
@@ -0,0 +1,75 @@
+package yago_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRawQuery(t *testing.T) {
+	db, model, cleanup := initModel(t)
+	defer cleanup()
+
+	for _, name := range []string{"John", "Jane", "Malcom"} {
+		assert.Nil(t, db.Insert(&PersonStruct{FirstName: name, Gender: Male}))
+	}
+
+	// The columns order differs from the mapper one, and an extra column
+	// is selected
+	raw := db.RawQuery(model.PersonStruct,
+		`SELECT 42 AS extra, updated_at, created_at, id, gender, last_name,
+		first_name, active
+		FROM person_struct WHERE first_name != ?`, "Malcom")
+
+	var p PersonStruct
+	assert.Nil(t, raw.Where(model.PersonStruct.FirstName.Eq("John")).One(&p))
+	assert.Equal(t, "John", p.FirstName)
+	assert.Equal(t, Male, p.Gender)
+
+	var persons []PersonStruct
+	assert.Nil(t, raw.OrderBy(model.PersonStruct.FirstName).All(&persons))
+	assert.Len(t, persons, 2)
+	assert.Equal(t, "Jane", persons[0].FirstName)
+
+	var count int
+	assert.Nil(t, raw.Count(&count))
+	assert.Equal(t, 2, count)
+
+	it, err := raw.OrderBy(model.PersonStruct.FirstName.Desc()).Iter()
+	assert.Nil(t, err)
+	defer it.Close()
+	var names []string
+	for it.Next() {
+		assert.Nil(t, it.Scan(&p))
+		names = append(names, p.FirstName)
+	}
+	assert.Nil(t, it.Err())
+	assert.Equal(t, []string{"John", "Jane"}, names)
+
+	// A missing column is reported
+	err = db.RawQuery(model.PersonStruct, "SELECT id FROM person_struct").One(&p)
+	assert.NotNil(t, err)
+}
+
+func TestRawQueryPostgres(t *testing.T) {
+	db, model, cleanup := initModelWithDriver(t, "postgres")
+	defer cleanup()
+
+	for _, name := range []string{"John", "Jane", "Malcom"} {
+		assert.Nil(t, db.Insert(&PersonStruct{FirstName: name, Gender: Male}))
+	}
+
+	// The raw placeholders are numbered with the Where ones
+	q := db.RawQuery(model.PersonStruct,
+		"SELECT * FROM person_struct WHERE first_name != $1", "Malcom").
+		Where(model.PersonStruct.FirstName.Eq("John"))
+	sql, binds, err := q.ToSQL()
+	assert.Nil(t, err)
+	assert.Contains(t, sql, "first_name != $1")
+	assert.Contains(t, sql, "= $2")
+	assert.Equal(t, []interface{}{"Malcom", "John"}, binds)
+
+	var p PersonStruct
+	assert.Nil(t, q.One(&p))
+	assert.Equal(t, "John", p.FirstName)
+}
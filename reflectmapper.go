@@ -0,0 +1,449 @@
+package yago
+
+import (
+	"database/sql"
+	"encoding"
+	"fmt"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/slicebit/qb"
+
+	"github.com/orus-io/yago/internal/tags"
+)
+
+// ReflectOptions are the options of ReflectMapper
+type ReflectOptions struct {
+	// Name is the mapper name. Defaults to "package/Struct"
+	Name string
+	// TableName defaults to the struct name converted with tags.ToDBName
+	TableName string
+	// AutoAttrs maps all the exported fields, and not only the tagged ones.
+	// It applies to the embedded structs too.
+	AutoAttrs bool
+	// Metadata is used to find the structs referenced by the foreign keys.
+	// It is not needed if the struct only references itself.
+	Metadata *Metadata
+}
+
+type reflectField struct {
+	name          string
+	index         []int
+	goType        reflect.Type
+	tags          tags.ColumnTags
+	columnName    string
+	textMarshaled bool
+}
+
+// reflectFields returns the mapped fields of a struct, following the
+// generator rules: the fields of the embedded structs of the same package
+// come after the struct own fields
+func reflectFields(t reflect.Type, autoAttrs bool) ([]reflectField, error) {
+	var (
+		fields   []reflectField
+		embedded []reflectField
+	)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("yago")
+		if f.Anonymous {
+			if tag != "" {
+				return nil, fmt.Errorf(
+					`%s has anonymous field %s with "yago:" tag, it is not allowed`, t.Name(), f.Name)
+			}
+			if f.Type.Kind() != reflect.Struct || f.Type.PkgPath() != t.PkgPath() {
+				continue
+			}
+			subFields, err := reflectFields(f.Type, autoAttrs)
+			if err != nil {
+				return nil, err
+			}
+			for _, subField := range subFields {
+				subField.index = append([]int{i}, subField.index...)
+				embedded = append(embedded, subField)
+			}
+			continue
+		}
+		if tag != "" && f.PkgPath != "" {
+			return nil, fmt.Errorf(
+				`%s has non-exported field %s with "yago:" tag, it is not allowed`, t.Name(), f.Name)
+		}
+		if !(tag != "" || autoAttrs && f.PkgPath == "") {
+			continue
+		}
+		field := reflectField{name: f.Name, index: []int{i}, goType: f.Type}
+		if tag != "" {
+			var err error
+			if field.tags, err = tags.ParseColumnTags(tag); err != nil {
+				return nil, fmt.Errorf("%s.%s: %s", t.Name(), f.Name, err)
+			}
+		}
+		field.columnName = field.tags.ColumnName
+		if field.columnName == "" {
+			field.columnName = tags.ToDBName(f.Name)
+		}
+		field.textMarshaled = field.tags.TextMarshaled
+		fields = append(fields, field)
+	}
+	return append(fields, embedded...), nil
+}
+
+// column builds the qb column of a field, like the generator does
+func (f reflectField) column() (qb.ColumnElem, error) {
+	var (
+		colType qb.TypeElem
+		err     error
+	)
+	goType := f.goType.String()
+	if f.textMarshaled {
+		goType = "string"
+	}
+	if f.tags.Type != "" {
+		colType, err = tags.ParseTypeExpr(f.tags.Type)
+	} else if t, ok := tags.TypesMap[goType]; ok {
+		colType, err = tags.ParseTypeExpr(t.ColumnName)
+	} else {
+		err = fmt.Errorf("Cannot guess column type for go type %s", goType)
+	}
+	if err != nil {
+		return qb.ColumnElem{}, fmt.Errorf("Failure on field '%s': %s", f.name, err)
+	}
+	col := qb.Column(f.columnName, colType)
+	if f.tags.PrimaryKey {
+		col = col.PrimaryKey()
+	}
+	if f.tags.AutoIncrement {
+		col = col.AutoIncrement()
+	}
+	if f.tags.Null {
+		col = col.Null()
+	} else if f.tags.NotNull {
+		col = col.NotNull()
+	} else if goType[0] == '*' || strings.Contains(goType, "Null") {
+		col = col.Null()
+	} else {
+		col = col.NotNull()
+	}
+	return col, nil
+}
+
+var (
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// reflectMapper is a Mapper built at runtime from the struct tags
+type reflectMapper struct {
+	name       string
+	structType reflect.Type
+	table      qb.TableElem
+	fields     []reflectField
+	pkey       []reflectField
+	autoInc    *reflectField
+}
+
+// ReflectMapper returns a Mapper built at runtime from the "yago:" tags of
+// a struct, without code generation. The tags and the resulting table are
+// the same as with the generator. s must be a pointer to a struct that
+// implements MappedStruct.
+func ReflectMapper(s MappedStruct, opts ReflectOptions) (Mapper, error) {
+	t := reflect.TypeOf(s)
+	if t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("yago ReflectMapper(): Expected a pointer to a struct, got %s", t)
+	}
+	t = t.Elem()
+	if t != s.StructType() {
+		return nil, fmt.Errorf(
+			"yago ReflectMapper(): %s.StructType() returns %s", t, s.StructType())
+	}
+
+	fields, err := reflectFields(t, opts.AutoAttrs)
+	if err != nil {
+		return nil, fmt.Errorf("yago ReflectMapper(): %s", err)
+	}
+
+	m := &reflectMapper{
+		name:       opts.Name,
+		structType: t,
+		fields:     fields,
+	}
+	if m.name == "" {
+		m.name = path.Base(t.PkgPath()) + "/" + t.Name()
+	}
+	tableName := opts.TableName
+	if tableName == "" {
+		tableName = tags.ToDBName(t.Name())
+	}
+
+	var (
+		clauses       []qb.TableClause
+		foreignKeys   []qb.TableClause
+		indexes       = make(map[string][]string)
+		uniqueIndexes = make(map[string][]string)
+	)
+	for i, f := range fields {
+		col, err := f.column()
+		if err != nil {
+			return nil, fmt.Errorf("yago ReflectMapper(): %s", err)
+		}
+		clauses = append(clauses, col)
+
+		if f.tags.PrimaryKey {
+			m.pkey = append(m.pkey, f)
+		}
+		if f.tags.AutoIncrement {
+			m.autoInc = &m.fields[i]
+		}
+		if f.textMarshaled && !(f.goType.Implements(textMarshalerType) &&
+			reflect.PtrTo(f.goType).Implements(textUnmarshalerType)) {
+			return nil, fmt.Errorf(
+				"yago ReflectMapper(): Field '%s' is textmarshaled but %s is not a TextMarshaler and TextUnmarshaler",
+				f.name, f.goType)
+		}
+		for _, index := range f.tags.Indexes {
+			indexes[index] = append(indexes[index], f.columnName)
+		}
+		// like the generator, the unique indexes of the embedded structs
+		// are ignored
+		if len(f.index) == 1 {
+			for _, index := range f.tags.UniqueIndexes {
+				uniqueIndexes[index] = append(uniqueIndexes[index], f.columnName)
+			}
+		}
+		for _, fkDef := range f.tags.ForeignKeys {
+			fk, err := m.foreignKey(t, tableName, f, fkDef, opts.Metadata)
+			if err != nil {
+				return nil, fmt.Errorf("yago ReflectMapper(): Field '%s': %s", f.name, err)
+			}
+			foreignKeys = append(foreignKeys, fk)
+		}
+	}
+	if len(m.pkey) == 0 {
+		return nil, fmt.Errorf("yago ReflectMapper(): No Primary Key found on %s", t.Name())
+	}
+	// the generated code lists the indexes in the names order
+	for _, name := range sortedKeys(uniqueIndexes) {
+		clauses = append(clauses, qb.UniqueKey(uniqueIndexes[name]...))
+	}
+	clauses = append(clauses, foreignKeys...)
+
+	m.table = qb.Table(tableName, clauses...)
+	for _, name := range sortedKeys(indexes) {
+		m.table = m.table.Index(indexes[name]...)
+	}
+	return m, nil
+}
+
+func sortedKeys(m map[string][]string) []string {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// foreignKey builds the foreign key constraint of a "fk=" tag. The
+// referenced struct is either t itself or a struct mapped in meta.
+func (m *reflectMapper) foreignKey(
+	t reflect.Type, tableName string, f reflectField, fkDef string, meta *Metadata,
+) (qb.ForeignKeyConstraint, error) {
+	fk, onUpdate, onDelete, err := tags.ParseFKDef(fkDef)
+	if err != nil {
+		return qb.ForeignKeyConstraint{}, err
+	}
+	structName, refFieldName := fk, ""
+	if i := strings.Index(fk, "."); i != -1 {
+		structName, refFieldName = fk[:i], fk[i+1:]
+	}
+
+	refType, refTable := t, tableName
+	if structName != t.Name() {
+		var refMapper Mapper
+		if meta != nil {
+			// the default mappers are registered first. A struct of the
+			// same package is preferred.
+			for _, mapper := range meta.registered {
+				st := mapper.StructType()
+				if st.Name() != structName {
+					continue
+				}
+				if refMapper == nil || st.PkgPath() == t.PkgPath() &&
+					refMapper.StructType().PkgPath() != t.PkgPath() {
+					refMapper = mapper
+				}
+			}
+		}
+		if refMapper == nil {
+			return qb.ForeignKeyConstraint{}, fmt.Errorf("Cannot resolve the referenced struct '%s'", structName)
+		}
+		refType, refTable = refMapper.StructType(), refMapper.Table().Name
+	}
+
+	refFields, err := reflectFields(refType, true)
+	if err != nil {
+		return qb.ForeignKeyConstraint{}, err
+	}
+	var refColumn string
+	for _, refField := range refFields {
+		if refFieldName == "" && refField.tags.PrimaryKey || refField.name == refFieldName {
+			refColumn = refField.columnName
+			break
+		}
+	}
+	if refColumn == "" {
+		return qb.ForeignKeyConstraint{}, fmt.Errorf("Cannot resolve the referenced field of '%s'", fk)
+	}
+
+	constraint := qb.ForeignKey(f.columnName).References(refTable, refColumn)
+	if onUpdate != "" {
+		constraint = constraint.OnUpdate(onUpdate)
+	}
+	if onDelete != "" {
+		constraint = constraint.OnDelete(onDelete)
+	}
+	return constraint, nil
+}
+
+// GetMapper returns itself
+func (m *reflectMapper) GetMapper() Mapper {
+	return m
+}
+
+// Name returns the mapper name
+func (m *reflectMapper) Name() string {
+	return m.name
+}
+
+// Table returns the mapper table
+func (m *reflectMapper) Table() *qb.TableElem {
+	return &m.table
+}
+
+// StructType returns the reflect.Type of the mapped structure
+func (m *reflectMapper) StructType() reflect.Type {
+	return m.structType
+}
+
+// value returns the addressable struct value of an instance
+func (m *reflectMapper) value(instance MappedStruct) reflect.Value {
+	v := reflect.ValueOf(instance)
+	if v.Type() != reflect.PtrTo(m.structType) {
+		panic(fmt.Sprintf(
+			"Wrong struct type passed to the mapper. Expected &%s{}, got %s",
+			m.structType.Name(), reflect.TypeOf(instance).Name(),
+		))
+	}
+	return v.Elem()
+}
+
+// SQLValues returns values as a map
+// The primary key is included only if having non-default values
+func (m *reflectMapper) SQLValues(instance MappedStruct, fields ...string) map[string]interface{} {
+	s := m.value(instance)
+	allValues := len(fields) == 0
+	values := make(map[string]interface{})
+	for _, f := range m.pkey {
+		if v := s.FieldByIndex(f.index); !v.IsZero() {
+			values[f.columnName] = v.Interface()
+		}
+	}
+	for _, f := range m.fields {
+		if f.tags.PrimaryKey || !(allValues || StringListContains(fields, f.name)) {
+			continue
+		}
+		v := s.FieldByIndex(f.index)
+		if f.textMarshaled {
+			b, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+			if err != nil {
+				panic(err)
+			}
+			values[f.columnName] = b
+		} else {
+			values[f.columnName] = v.Interface()
+		}
+	}
+	return values
+}
+
+// FieldList returns the list of fields for a select
+func (m *reflectMapper) FieldList() []qb.Clause {
+	var fields []qb.Clause
+	for _, f := range m.fields {
+		fields = append(fields, m.table.C(f.columnName))
+	}
+	return fields
+}
+
+// ScanPKey scans the primary key only
+func (m *reflectMapper) ScanPKey(rows *sql.Rows, instance MappedStruct) error {
+	s := m.value(instance)
+	var dest []interface{}
+	for _, f := range m.pkey {
+		dest = append(dest, s.FieldByIndex(f.index).Addr().Interface())
+	}
+	return rows.Scan(dest...)
+}
+
+// Scan a struct
+func (m *reflectMapper) Scan(rows *sql.Rows, instance MappedStruct) error {
+	s := m.value(instance)
+	dest := make([]interface{}, len(m.fields))
+	texts := make([][]byte, len(m.fields))
+	for i, f := range m.fields {
+		if f.textMarshaled {
+			dest[i] = &texts[i]
+		} else {
+			dest[i] = s.FieldByIndex(f.index).Addr().Interface()
+		}
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return err
+	}
+	for i, f := range m.fields {
+		if f.textMarshaled {
+			u := s.FieldByIndex(f.index).Addr().Interface().(encoding.TextUnmarshaler)
+			if err := u.UnmarshalText(texts[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// AutoIncrementPKey return true if a column of the pkey is autoincremented
+func (m *reflectMapper) AutoIncrementPKey() bool {
+	return m.autoInc != nil
+}
+
+// LoadAutoIncrementPKeyValue set the pkey autoincremented column value
+func (m *reflectMapper) LoadAutoIncrementPKeyValue(instance MappedStruct, value int64) {
+	if m.autoInc == nil {
+		panic(m.structType.Name() + " has no auto increment column in its pkey")
+	}
+	v := m.value(instance).FieldByIndex(m.autoInc.index)
+	v.Set(reflect.ValueOf(value).Convert(v.Type()))
+}
+
+// PKey returns the instance primary key values
+func (m *reflectMapper) PKey(instance MappedStruct) (values []interface{}) {
+	s := m.value(instance)
+	for _, f := range m.pkey {
+		values = append(values, s.FieldByIndex(f.index).Interface())
+	}
+	return
+}
+
+// PKeyClause returns a clause that matches the instance primary key
+func (m *reflectMapper) PKeyClause(values []interface{}) qb.Clause {
+	if len(m.pkey) == 1 {
+		return m.table.C(m.pkey[0].columnName).Eq(values[0])
+	}
+	var clauses []qb.Clause
+	for i, f := range m.pkey {
+		clauses = append(clauses, m.table.C(f.columnName).Eq(values[i]))
+	}
+	return qb.And(clauses...)
+}
@@ -0,0 +1,154 @@
+package yago_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/orus-io/yago"
+	"github.com/stretchr/testify/assert"
+)
+
+func reflectFixtureMappers(t *testing.T, meta *yago.Metadata) (simple, person, child yago.Mapper) {
+	var err error
+	simple, err = yago.ReflectMapper(&SimpleStruct{}, yago.ReflectOptions{})
+	assert.Nil(t, err)
+	meta.AddMapper(simple)
+	person, err = yago.ReflectMapper(&PersonStruct{}, yago.ReflectOptions{AutoAttrs: true})
+	assert.Nil(t, err)
+	meta.AddMapper(person)
+	child, err = yago.ReflectMapper(&AutoIncChild{}, yago.ReflectOptions{AutoAttrs: true, Metadata: meta})
+	assert.Nil(t, err)
+	meta.AddMapper(child)
+	return
+}
+
+func TestReflectMapperParity(t *testing.T) {
+	simple, person, child := reflectFixtureMappers(t, yago.NewMetadata())
+
+	p := PersonStruct{FirstName: "John", LastName: "Doe", Gender: Female}
+	p.BeforeInsert(nil)
+	for _, tt := range []struct {
+		generated yago.Mapper
+		reflected yago.Mapper
+		instance  yago.MappedStruct
+	}{
+		{NewSimpleStructMapper(), simple, &SimpleStruct{ID: 2, Name: "name", Counter: 3}},
+		{NewPersonStructMapper(), person, &p},
+		{NewAutoIncChildMapper(), child, &AutoIncChild{Name: "child", Person: p.ID}},
+	} {
+		generated, reflected := tt.generated, tt.reflected
+		assert.Equal(t, generated.Name(), reflected.Name())
+		assert.Equal(t, generated.Table(), reflected.Table())
+		assert.Equal(t, generated.StructType(), reflected.StructType())
+		assert.Equal(t, generated.FieldList(), reflected.FieldList())
+		assert.Equal(t, generated.AutoIncrementPKey(), reflected.AutoIncrementPKey())
+
+		assert.Equal(t, generated.SQLValues(tt.instance), reflected.SQLValues(tt.instance))
+		assert.Equal(t,
+			generated.SQLValues(tt.instance, "Name"),
+			reflected.SQLValues(tt.instance, "Name"))
+		assert.Equal(t, generated.PKey(tt.instance), reflected.PKey(tt.instance))
+		pkey := generated.PKey(tt.instance)
+		assert.Equal(t, generated.PKeyClause(pkey), reflected.PKeyClause(pkey))
+	}
+
+	s := SimpleStruct{}
+	simple.LoadAutoIncrementPKeyValue(&s, 12)
+	assert.Equal(t, int64(12), s.ID)
+	assert.Panics(t, func() { person.LoadAutoIncrementPKeyValue(&p, 1) })
+	assert.Panics(t, func() { simple.SQLValues(&p) })
+}
+
+func TestReflectMapperDB(t *testing.T) {
+	engine := initSqliteEngine(t)
+	defer engine.Close()
+
+	meta := yago.NewMetadata()
+	_, person, child := reflectFixtureMappers(t, meta)
+	db := yago.New(meta, engine)
+	assert.Nil(t, meta.GetQbMetadata().CreateAll(engine))
+
+	p := PersonStruct{FirstName: "John", Gender: Male}
+	assert.Nil(t, db.Insert(&p))
+	c := AutoIncChild{Name: "child", Person: p.ID}
+	assert.Nil(t, db.Insert(&c))
+	assert.NotEqual(t, int64(0), c.ID)
+
+	var p1 PersonStruct
+	assert.Nil(t, db.Query(person).Get(&p1, p.ID))
+	assert.Equal(t, p.FirstName, p1.FirstName)
+	assert.Equal(t, Male, p1.Gender)
+
+	first := yago.NewScalarField(person.Table().C("first_name"))
+	p1.LastName = "Doe"
+	assert.Nil(t, db.Update(&p1))
+	assert.Nil(t, db.Query(person).Where(first.Eq("John")).One(&p1))
+	assert.Equal(t, "Doe", p1.LastName)
+
+	var children []AutoIncChild
+	assert.Nil(t, db.Query(child).All(&children))
+	assert.Equal(t, []AutoIncChild{c}, children)
+
+	assert.Nil(t, db.Delete(&c))
+	var count int
+	assert.Nil(t, db.Query(child).Count(&count))
+	assert.Equal(t, 0, count)
+}
+
+type reflectNoPKey struct {
+	Name string `yago:"index"`
+}
+
+func (reflectNoPKey) StructType() reflect.Type {
+	return reflect.TypeOf(reflectNoPKey{})
+}
+
+type reflectBadType struct {
+	ID   int64   `yago:"primary_key"`
+	Rate float64 `yago:"rate"`
+}
+
+func (reflectBadType) StructType() reflect.Type {
+	return reflect.TypeOf(reflectBadType{})
+}
+
+type reflectTypeTag struct {
+	ID   int64   `yago:"primary_key,auto_increment"`
+	Rate float64 `yago:"type=qb.Decimal()"`
+	Code string  `yago:"type=qb.Varchar().Size(8),unique_index=code"`
+	Tree int64   `yago:"fk=reflectTypeTag ONDELETE CASCADE,null"`
+}
+
+func (reflectTypeTag) StructType() reflect.Type {
+	return reflect.TypeOf(reflectTypeTag{})
+}
+
+func TestReflectMapperErrors(t *testing.T) {
+	for _, tt := range []struct {
+		s    yago.MappedStruct
+		opts yago.ReflectOptions
+		err  string
+	}{
+		{SimpleStruct{}, yago.ReflectOptions{},
+			"yago ReflectMapper(): Expected a pointer to a struct, got yago_test.SimpleStruct"},
+		{&reflectNoPKey{}, yago.ReflectOptions{},
+			"yago ReflectMapper(): No Primary Key found on reflectNoPKey"},
+		{&reflectBadType{}, yago.ReflectOptions{},
+			"yago ReflectMapper(): Failure on field 'Rate': Cannot guess column type for go type float64"},
+		{&AutoIncChild{}, yago.ReflectOptions{AutoAttrs: true},
+			"yago ReflectMapper(): Field 'Person': Cannot resolve the referenced struct 'PersonStruct'"},
+	} {
+		_, err := yago.ReflectMapper(tt.s, tt.opts)
+		if assert.NotNil(t, err) {
+			assert.Equal(t, tt.err, err.Error())
+		}
+	}
+
+	mapper, err := yago.ReflectMapper(&reflectTypeTag{}, yago.ReflectOptions{TableName: "tree"})
+	assert.Nil(t, err)
+	assert.Equal(t, "tree", mapper.Table().Name)
+	assert.Equal(t, "yago_test/reflectTypeTag", mapper.Name())
+	if assert.Len(t, mapper.Table().ForeignKeyConstraints.FKeys, 1) {
+		assert.Equal(t, "tree", mapper.Table().ForeignKeyConstraints.FKeys[0].RefTable)
+	}
+}
@@ -0,0 +1,520 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/slicebit/qb"
+)
+
+// ChangeKind is the kind of a schema change
+type ChangeKind string
+
+// The schema change kinds
+const (
+	CreateTable     ChangeKind = "create table"
+	DropTable       ChangeKind = "drop table"
+	AddColumn       ChangeKind = "add column"
+	DropColumn      ChangeKind = "drop column"
+	AlterColumn     ChangeKind = "alter column"
+	AlterPrimaryKey ChangeKind = "alter primary key"
+	CreateIndex     ChangeKind = "create index"
+	DropIndex       ChangeKind = "drop index"
+	AddForeignKey   ChangeKind = "add foreign key"
+	DropForeignKey  ChangeKind = "drop foreign key"
+)
+
+// Change is a difference between two schemas, and the statements that
+// apply (Up) and revert (Down) it
+type Change struct {
+	Kind  ChangeKind
+	Table string
+	// Name is the column, index or foreign key name
+	Name   string
+	Detail string
+	// Destructive is true if applying the change may lose data
+	Destructive bool
+	// Review explains why the change needs a manual review, if it does:
+	// the statements may fail, or are missing because the database cannot
+	// do the change
+	Review string
+
+	Up   []string
+	Down []string
+}
+
+func (c Change) String() string {
+	s := string(c.Kind) + " " + c.Table
+	if c.Name != "" {
+		s += "." + c.Name
+	}
+	if c.Detail != "" {
+		s += ": " + c.Detail
+	}
+	return s
+}
+
+const sqliteRebuild = "sqlite cannot do this change, the table must be rebuilt"
+
+// differ computes the changes between two schemas
+type differ struct {
+	dialect qb.Dialect
+	driver  string
+	schema  string
+	changes map[ChangeKind][]Change
+}
+
+// Diff returns the changes that turn the current schema into the target
+// one. The statements are written for dialect, which must be a sqlite3 or
+// a postgres one.
+// The changes are ordered so they can be applied in sequence: the foreign
+// keys and indexes are dropped first, and the columns and tables last.
+func Diff(current *Schema, target *Schema, dialect qb.Dialect) []Change {
+	d := differ{
+		dialect: dialect,
+		driver:  dialect.Driver(),
+		changes: make(map[ChangeKind][]Change),
+	}
+
+	var created []*Table
+	for _, t := range target.Tables {
+		if cur := current.Table(t.Name); cur != nil {
+			d.diffTable(cur, t)
+		} else {
+			created = append(created, t)
+		}
+	}
+	created, _ = SortByDependencies(created)
+	for _, t := range created {
+		d.add(Change{
+			Kind:  CreateTable,
+			Table: t.Name,
+			Up:    d.createTable(t),
+			Down:  []string{"DROP TABLE " + d.esc(t.Name)},
+		})
+	}
+	var dropped []*Table
+	for _, t := range current.Tables {
+		if target.Table(t.Name) == nil {
+			dropped = append(dropped, t)
+		}
+	}
+	// the referencing tables are dropped first
+	dropped, _ = SortByDependencies(dropped)
+	for i := len(dropped) - 1; i >= 0; i-- {
+		t := dropped[i]
+		d.add(Change{
+			Kind:        DropTable,
+			Table:       t.Name,
+			Destructive: true,
+			Review:      "the table data is lost",
+			Up:          []string{"DROP TABLE " + d.esc(t.Name)},
+			Down:        d.createTable(t),
+		})
+	}
+
+	var changes []Change
+	for _, kind := range []ChangeKind{
+		DropForeignKey, DropIndex, CreateTable, AddColumn, AlterColumn,
+		AlterPrimaryKey, CreateIndex, AddForeignKey, DropColumn, DropTable,
+	} {
+		changes = append(changes, d.changes[kind]...)
+	}
+	return changes
+}
+
+// DDL returns the statements that create the tables of s and their
+// indexes, the referenced tables first. The statements are written for
+// dialect, which must be a sqlite3, postgres or mysql one.
+// On postgres and mysql, the foreign keys that close a dependency cycle
+// are added once all the tables are created.
+func (s *Schema) DDL(dialect qb.Dialect) []string {
+	d := differ{dialect: dialect, driver: dialect.Driver(), schema: s.Name}
+	tables, cycles := SortByDependencies(s.Tables)
+	if d.driver == "sqlite3" {
+		// sqlite does not check the referenced tables on creation
+		cycles = nil
+	}
+	var statements []string
+	for _, t := range tables {
+		created := *t
+		created.ForeignKeys = nil
+		for _, fk := range t.ForeignKeys {
+			if !isCycle(cycles, t.Name, fk) {
+				created.ForeignKeys = append(created.ForeignKeys, fk)
+			}
+		}
+		statements = append(statements, d.createTable(&created)...)
+	}
+	for _, fk := range cycles {
+		statements = append(statements, fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s %s",
+			d.table(fk.Table), d.esc(foreignKeyName(fk.Table, fk.ForeignKey)), d.foreignKeyDef(fk.ForeignKey)))
+	}
+	return statements
+}
+
+func isCycle(cycles []TableForeignKey, table string, fk ForeignKey) bool {
+	for _, c := range cycles {
+		if c.Table == table && findForeignKey([]ForeignKey{c.ForeignKey}, fk) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *differ) add(c Change) {
+	d.changes[c.Kind] = append(d.changes[c.Kind], c)
+}
+
+func (d *differ) esc(name string) string {
+	return d.dialect.Escape(name)
+}
+
+func (d *differ) escAll(names []string) string {
+	return strings.Join(d.dialect.EscapeAll(names), ", ")
+}
+
+// table escapes a table name, qualified with the schema name
+func (d *differ) table(name string) string {
+	if d.schema == "" {
+		return d.esc(name)
+	}
+	return d.esc(d.schema) + "." + d.esc(name)
+}
+
+func (d *differ) diffTable(cur *Table, target *Table) {
+	table := target.Name
+	alterTable := "ALTER TABLE " + d.esc(table) + " "
+
+	for _, col := range target.Columns {
+		curCol := cur.Column(col.Name)
+		if curCol == nil {
+			c := Change{
+				Kind:  AddColumn,
+				Table: table,
+				Name:  col.Name,
+				Up:    []string{alterTable + "ADD COLUMN " + d.columnDef(col, false)},
+				Down:  []string{alterTable + "DROP COLUMN " + d.esc(col.Name)},
+			}
+			if !col.Nullable && col.Default == "" {
+				c.Review = "adding a NOT NULL column without default fails if the table has rows"
+			}
+			d.add(c)
+			continue
+		}
+		if !SameType(d.driver, curCol.Type, col.Type) {
+			c := Change{
+				Kind:        AlterColumn,
+				Table:       table,
+				Name:        col.Name,
+				Detail:      fmt.Sprintf("type %s -> %s", curCol.Type, col.Type),
+				Destructive: true,
+				Review:      "the values may not be convertible to the new type",
+			}
+			if d.driver == "postgres" {
+				c.Up = []string{fmt.Sprintf("%sALTER COLUMN %s TYPE %s USING %s::%s",
+					alterTable, d.esc(col.Name), col.Type, d.esc(col.Name), col.Type)}
+				c.Down = []string{fmt.Sprintf("%sALTER COLUMN %s TYPE %s USING %s::%s",
+					alterTable, d.esc(col.Name), curCol.Type, d.esc(col.Name), curCol.Type)}
+			} else {
+				c.Review = sqliteRebuild
+			}
+			d.add(c)
+		}
+		if curCol.Nullable != col.Nullable {
+			c := Change{
+				Kind:  AlterColumn,
+				Table: table,
+				Name:  col.Name,
+			}
+			setNotNull := alterTable + "ALTER COLUMN " + d.esc(col.Name) + " SET NOT NULL"
+			dropNotNull := alterTable + "ALTER COLUMN " + d.esc(col.Name) + " DROP NOT NULL"
+			if col.Nullable {
+				c.Detail = "drop NOT NULL"
+				c.Up, c.Down = []string{dropNotNull}, []string{setNotNull}
+			} else {
+				c.Detail = "set NOT NULL"
+				c.Review = "fails if the column contains NULL values"
+				c.Up, c.Down = []string{setNotNull}, []string{dropNotNull}
+			}
+			if d.driver != "postgres" {
+				c.Up, c.Down, c.Review = nil, nil, sqliteRebuild
+			}
+			d.add(c)
+		}
+	}
+	for _, col := range cur.Columns {
+		if target.Column(col.Name) == nil {
+			d.add(Change{
+				Kind:        DropColumn,
+				Table:       table,
+				Name:        col.Name,
+				Destructive: true,
+				Review:      "the column data is lost",
+				Up:          []string{alterTable + "DROP COLUMN " + d.esc(col.Name)},
+				Down:        []string{alterTable + "ADD COLUMN " + d.columnDef(col, false)},
+			})
+		}
+	}
+
+	if !sameColumns(cur.PrimaryKey, target.PrimaryKey) {
+		d.add(Change{
+			Kind:   AlterPrimaryKey,
+			Table:  table,
+			Detail: fmt.Sprintf("(%s) -> (%s)", strings.Join(cur.PrimaryKey, ", "), strings.Join(target.PrimaryKey, ", ")),
+			Review: "the primary key must be changed manually",
+		})
+	}
+
+	for _, index := range target.Indexes {
+		if findIndex(cur.Indexes, index) == nil {
+			index.Name = IndexName(table, index)
+			d.add(Change{
+				Kind:  CreateIndex,
+				Table: table,
+				Name:  index.Name,
+				Up:    []string{d.createIndex(table, index)},
+				Down:  []string{"DROP INDEX " + d.esc(index.Name)},
+			})
+		}
+	}
+	for _, index := range cur.Indexes {
+		if findIndex(target.Indexes, index) == nil {
+			c := Change{
+				Kind:  DropIndex,
+				Table: table,
+				Name:  index.Name,
+				Up:    []string{"DROP INDEX " + d.esc(index.Name)},
+				Down:  []string{d.createIndex(table, index)},
+			}
+			if index.Constraint {
+				if d.driver == "postgres" {
+					c.Up = []string{alterTable + "DROP CONSTRAINT " + d.esc(index.Name)}
+					c.Down = []string{fmt.Sprintf("%sADD CONSTRAINT %s UNIQUE (%s)",
+						alterTable, d.esc(index.Name), d.escAll(index.Columns))}
+				} else {
+					c.Up, c.Down, c.Review = nil, nil, sqliteRebuild
+				}
+			}
+			d.add(c)
+		}
+	}
+
+	for _, fk := range target.ForeignKeys {
+		if findForeignKey(cur.ForeignKeys, fk) == nil {
+			fk.Name = foreignKeyName(table, fk)
+			c := Change{
+				Kind:  AddForeignKey,
+				Table: table,
+				Name:  fk.Name,
+				Up: []string{fmt.Sprintf("%sADD CONSTRAINT %s %s",
+					alterTable, d.esc(fk.Name), d.foreignKeyDef(fk))},
+				Down: []string{alterTable + "DROP CONSTRAINT " + d.esc(fk.Name)},
+			}
+			if d.driver != "postgres" {
+				c.Up, c.Down, c.Review = nil, nil, sqliteRebuild
+			}
+			d.add(c)
+		}
+	}
+	for _, fk := range cur.ForeignKeys {
+		if findForeignKey(target.ForeignKeys, fk) == nil {
+			c := Change{
+				Kind:  DropForeignKey,
+				Table: table,
+				Name:  fk.Name,
+				Up:    []string{alterTable + "DROP CONSTRAINT " + d.esc(fk.Name)},
+				Down: []string{fmt.Sprintf("%sADD CONSTRAINT %s %s",
+					alterTable, d.esc(fk.Name), d.foreignKeyDef(fk))},
+			}
+			if d.driver != "postgres" {
+				c.Up, c.Down, c.Review = nil, nil, sqliteRebuild
+			}
+			d.add(c)
+		}
+	}
+}
+
+func findIndex(indexes []Index, index Index) *Index {
+	for i := range indexes {
+		if indexes[i].Unique == index.Unique && sameColumns(indexes[i].Columns, index.Columns) {
+			return &indexes[i]
+		}
+	}
+	return nil
+}
+
+// IndexName returns the name of an index of a table, generated from its
+// columns if it has none
+func IndexName(table string, index Index) string {
+	if index.Name != "" {
+		return index.Name
+	}
+	prefix := "i_"
+	if index.Unique {
+		prefix = "u_"
+	}
+	return prefix + table + "_" + strings.Join(index.Columns, "_")
+}
+
+// findForeignKey finds a foreign key by columns, references and actions
+func findForeignKey(fks []ForeignKey, fk ForeignKey) *ForeignKey {
+	for i := range fks {
+		if fks[i].RefTable == fk.RefTable &&
+			sameColumns(fks[i].Columns, fk.Columns) &&
+			sameColumns(fks[i].RefColumns, fk.RefColumns) &&
+			fks[i].OnUpdate == fk.OnUpdate && fks[i].OnDelete == fk.OnDelete {
+			return &fks[i]
+		}
+	}
+	return nil
+}
+
+func foreignKeyName(table string, fk ForeignKey) string {
+	if fk.Name != "" {
+		return fk.Name
+	}
+	return "fk_" + table + "_" + strings.Join(fk.Columns, "_")
+}
+
+// columnDef returns a column definition. The autoincrement is rendered
+// only in a CREATE TABLE statement.
+func (d *differ) columnDef(col Column, inCreate bool) string {
+	colType := col.Type
+	if inCreate && col.AutoIncrement && d.driver == "postgres" {
+		switch NormalizeType(d.driver, colType) {
+		case "SMALLINT":
+			colType = "SMALLSERIAL"
+		case "INT":
+			colType = "SERIAL"
+		case "BIGINT":
+			colType = "BIGSERIAL"
+		}
+	}
+	def := d.esc(col.Name) + " " + colType
+	if !col.Nullable {
+		def += " NOT NULL"
+	}
+	if inCreate && col.AutoIncrement && d.driver == "mysql" {
+		def += " AUTO_INCREMENT"
+	}
+	if col.Default != "" && !(col.AutoIncrement && d.driver == "postgres") {
+		def += " DEFAULT " + col.Default
+	}
+	return def
+}
+
+func (d *differ) foreignKeyDef(fk ForeignKey) string {
+	// sqlite only references the tables of the same database, unqualified
+	refTable := d.table(fk.RefTable)
+	if d.driver == "sqlite3" {
+		refTable = d.esc(fk.RefTable)
+	}
+	def := fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s (%s)",
+		d.escAll(fk.Columns), refTable, d.escAll(fk.RefColumns))
+	if fk.OnUpdate != "" {
+		def += " ON UPDATE " + fk.OnUpdate
+	}
+	if fk.OnDelete != "" {
+		def += " ON DELETE " + fk.OnDelete
+	}
+	return def
+}
+
+func (d *differ) createIndex(table string, index Index) string {
+	create := "CREATE INDEX "
+	if index.Unique {
+		create = "CREATE UNIQUE INDEX "
+	}
+	// sqlite qualifies the index name, the other databases the table name
+	name, on := d.esc(IndexName(table, index)), d.table(table)
+	if d.driver == "sqlite3" {
+		name, on = d.table(IndexName(table, index)), d.esc(table)
+	}
+	return fmt.Sprintf("%s%s ON %s (%s)", create, name, on, d.escAll(index.Columns))
+}
+
+// createTable returns the statements that create a table and its indexes
+func (d *differ) createTable(t *Table) []string {
+	var defs []string
+	sqliteAutoIncrement := false
+	for _, col := range t.Columns {
+		def := d.columnDef(col, true)
+		if col.AutoIncrement && d.driver == "sqlite3" && len(t.PrimaryKey) == 1 {
+			def = d.esc(col.Name) + " INTEGER PRIMARY KEY AUTOINCREMENT"
+			sqliteAutoIncrement = true
+		}
+		defs = append(defs, def)
+	}
+	if len(t.PrimaryKey) != 0 && !sqliteAutoIncrement {
+		defs = append(defs, "PRIMARY KEY ("+d.escAll(t.PrimaryKey)+")")
+	}
+	var indexes []string
+	for _, index := range t.Indexes {
+		if index.Constraint && strings.HasPrefix(index.Name, "sqlite_autoindex_") {
+			// the sqlite names are reserved
+			defs = append(defs, fmt.Sprintf("UNIQUE (%s)", d.escAll(index.Columns)))
+		} else if index.Constraint {
+			defs = append(defs, fmt.Sprintf("CONSTRAINT %s UNIQUE (%s)",
+				d.esc(IndexName(t.Name, index)), d.escAll(index.Columns)))
+		} else {
+			indexes = append(indexes, d.createIndex(t.Name, index))
+		}
+	}
+	for _, fk := range t.ForeignKeys {
+		defs = append(defs, d.foreignKeyDef(fk))
+	}
+	create := fmt.Sprintf("CREATE TABLE %s (\n\t%s\n)", d.table(t.Name), strings.Join(defs, ",\n\t"))
+	return append([]string{create}, indexes...)
+}
+
+// TableForeignKey is a foreign key of a table
+type TableForeignKey struct {
+	Table string
+	ForeignKey
+}
+
+// SortByDependencies orders tables so the referenced tables come first.
+// The tables of a cycle keep their order. The returned foreign keys are the
+// ones that reference a table that comes later, closing a dependency cycle;
+// the self-references are not cycles.
+func SortByDependencies(tables []*Table) ([]*Table, []TableForeignKey) {
+	var (
+		sorted  []*Table
+		visited = make(map[*Table]bool)
+		visit   func(t *Table)
+	)
+	byName := make(map[string]*Table)
+	for _, t := range tables {
+		byName[t.Name] = t
+	}
+	visit = func(t *Table) {
+		if visited[t] {
+			return
+		}
+		visited[t] = true
+		for _, fk := range t.ForeignKeys {
+			if ref, ok := byName[fk.RefTable]; ok {
+				visit(ref)
+			}
+		}
+		sorted = append(sorted, t)
+	}
+	for _, t := range tables {
+		visit(t)
+	}
+
+	position := make(map[string]int)
+	for i, t := range sorted {
+		position[t.Name] = i
+	}
+	var cycles []TableForeignKey
+	for i, t := range sorted {
+		for _, fk := range t.ForeignKeys {
+			if ref, ok := position[fk.RefTable]; ok && ref > i {
+				cycles = append(cycles, TableForeignKey{Table: t.Name, ForeignKey: fk})
+			}
+		}
+	}
+	return sorted, cycles
+}
@@ -0,0 +1,406 @@
+package schema
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Inspect introspects the tables of a sqlite3 or postgres database.
+// schemaName is the postgres schema, or the sqlite attached database. If
+// empty, the current schema (or the main database) is inspected.
+func Inspect(db *sql.DB, driver string, schemaName string) (*Schema, error) {
+	var (
+		s   *Schema
+		err error
+	)
+	switch driver {
+	case "sqlite3":
+		s, err = inspectSqlite(db, schemaName)
+	case "postgres":
+		s, err = inspectPostgres(db, schemaName)
+	default:
+		return nil, fmt.Errorf("yago schema.Inspect(): Unsupported driver '%s'", driver)
+	}
+	if err != nil {
+		return nil, err
+	}
+	sortTables(s.Tables)
+	return s, nil
+}
+
+// TableNames returns the names of the tables of a sqlite3, postgres or
+// mysql database, in schemaName or in the current schema if empty
+func TableNames(db *sql.DB, driver string, schemaName string) ([]string, error) {
+	var (
+		query string
+		args  []interface{}
+	)
+	switch driver {
+	case "sqlite3":
+		prefix := ""
+		if schemaName != "" {
+			prefix = quoteSqlite(schemaName) + "."
+		}
+		query = fmt.Sprintf(
+			"SELECT name FROM %ssqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%%' ORDER BY name",
+			prefix)
+	case "postgres":
+		query = `SELECT table_name FROM information_schema.tables
+WHERE table_type = 'BASE TABLE' AND table_schema = COALESCE(NULLIF($1, ''), current_schema())
+ORDER BY table_name`
+		args = append(args, schemaName)
+	case "mysql":
+		query = `SELECT table_name FROM information_schema.tables
+WHERE table_type = 'BASE TABLE' AND table_schema = COALESCE(NULLIF(?, ''), DATABASE())
+ORDER BY table_name`
+		args = append(args, schemaName)
+	default:
+		return nil, fmt.Errorf("yago schema.TableNames(): Unsupported driver '%s'", driver)
+	}
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+func quoteSqlite(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+func inspectSqlite(db *sql.DB, schemaName string) (*Schema, error) {
+	prefix := ""
+	if schemaName != "" {
+		prefix = quoteSqlite(schemaName) + "."
+	}
+	pragma := func(name string, arg string) string {
+		return fmt.Sprintf("PRAGMA %s%s(%s)", prefix, name, quoteSqlite(arg))
+	}
+
+	var names []string
+	rows, err := db.Query(fmt.Sprintf(
+		"SELECT name, sql FROM %ssqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%%' ORDER BY name",
+		prefix))
+	if err != nil {
+		return nil, err
+	}
+	autoIncrement := make(map[string]bool)
+	for rows.Next() {
+		var (
+			name string
+			ddl  sql.NullString
+		)
+		if err := rows.Scan(&name, &ddl); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		names = append(names, name)
+		autoIncrement[name] = strings.Contains(strings.ToUpper(ddl.String), "AUTOINCREMENT")
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	s := &Schema{}
+	for _, name := range names {
+		t := &Table{Name: name}
+
+		rows, err := db.Query(pragma("table_info", name))
+		if err != nil {
+			return nil, err
+		}
+		var pkey []struct {
+			pos  int
+			name string
+		}
+		for rows.Next() {
+			var (
+				cid, notNull, pk int
+				col              Column
+				dflt             sql.NullString
+			)
+			if err := rows.Scan(&cid, &col.Name, &col.Type, &notNull, &dflt, &pk); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			col.Nullable = notNull == 0 && pk == 0
+			col.Default = dflt.String
+			if pk != 0 {
+				pkey = append(pkey, struct {
+					pos  int
+					name string
+				}{pk, col.Name})
+			}
+			t.Columns = append(t.Columns, col)
+		}
+		rows.Close()
+		t.PrimaryKey = make([]string, len(pkey))
+		for _, pk := range pkey {
+			t.PrimaryKey[pk.pos-1] = pk.name
+		}
+		// only an INTEGER PRIMARY KEY column can be autoincremented
+		if len(t.PrimaryKey) == 1 && autoIncrement[name] {
+			t.Column(t.PrimaryKey[0]).AutoIncrement = true
+		}
+
+		type indexInfo struct {
+			name   string
+			unique bool
+			origin string
+		}
+		var indexes []indexInfo
+		rows, err = db.Query(pragma("index_list", name))
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var (
+				seq, unique, partial int
+				index                indexInfo
+			)
+			if err := rows.Scan(&seq, &index.name, &unique, &index.origin, &partial); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			index.unique = unique != 0
+			if index.origin != "pk" {
+				indexes = append(indexes, index)
+			}
+		}
+		rows.Close()
+		for _, info := range indexes {
+			index := Index{Name: info.name, Unique: info.unique, Constraint: info.origin == "u"}
+			rows, err := db.Query(pragma("index_info", info.name))
+			if err != nil {
+				return nil, err
+			}
+			for rows.Next() {
+				var (
+					seqno, cid int
+					colName    sql.NullString
+				)
+				if err := rows.Scan(&seqno, &cid, &colName); err != nil {
+					rows.Close()
+					return nil, err
+				}
+				index.Columns = append(index.Columns, colName.String)
+			}
+			rows.Close()
+			t.Indexes = append(t.Indexes, index)
+		}
+
+		rows, err = db.Query(pragma("foreign_key_list", name))
+		if err != nil {
+			return nil, err
+		}
+		fks := make(map[int]*ForeignKey)
+		var fkIDs []int
+		for rows.Next() {
+			var (
+				id, seq                                int
+				refTable, from, onUpdate, onDelete, mt string
+				to                                     sql.NullString
+			)
+			if err := rows.Scan(&id, &seq, &refTable, &from, &to, &onUpdate, &onDelete, &mt); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			fk, ok := fks[id]
+			if !ok {
+				fk = &ForeignKey{RefTable: refTable, OnUpdate: onUpdate, OnDelete: onDelete}
+				fks[id] = fk
+				fkIDs = append(fkIDs, id)
+			}
+			fk.Columns = append(fk.Columns, from)
+			fk.RefColumns = append(fk.RefColumns, to.String)
+		}
+		rows.Close()
+		// sqlite lists the foreign keys in reverse order
+		for i := len(fkIDs) - 1; i >= 0; i-- {
+			fk := fks[fkIDs[i]]
+			fk.OnUpdate, fk.OnDelete = normalizeAction(fk.OnUpdate), normalizeAction(fk.OnDelete)
+			t.ForeignKeys = append(t.ForeignKeys, *fk)
+		}
+
+		s.Tables = append(s.Tables, t)
+	}
+
+	// a foreign key without columns references the primary key
+	for _, t := range s.Tables {
+		for i := range t.ForeignKeys {
+			fk := &t.ForeignKeys[i]
+			if ref := s.Table(fk.RefTable); ref != nil && fk.RefColumns[0] == "" {
+				fk.RefColumns = ref.PrimaryKey
+			}
+		}
+	}
+	return s, nil
+}
+
+func normalizeAction(action string) string {
+	action = strings.ToUpper(action)
+	if action == "NO ACTION" {
+		return ""
+	}
+	return action
+}
+
+const (
+	postgresColumnsQuery = `
+SELECT c.relname, a.attname, format_type(a.atttypid, a.atttypmod), a.attnotnull,
+	COALESCE(pg_get_expr(d.adbin, d.adrelid), '')
+FROM pg_attribute a
+JOIN pg_class c ON c.oid = a.attrelid
+JOIN pg_namespace n ON n.oid = c.relnamespace
+LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+WHERE c.relkind = 'r' AND n.nspname = COALESCE(NULLIF($1, ''), current_schema())
+	AND a.attnum > 0 AND NOT a.attisdropped
+ORDER BY c.relname, a.attnum`
+
+	postgresIndexesQuery = `
+SELECT t.relname, i.relname, ix.indisunique, ix.indisprimary,
+	EXISTS (SELECT 1 FROM pg_constraint c WHERE c.conindid = ix.indexrelid),
+	a.attname
+FROM pg_index ix
+JOIN pg_class t ON t.oid = ix.indrelid
+JOIN pg_class i ON i.oid = ix.indexrelid
+JOIN pg_namespace n ON n.oid = t.relnamespace
+JOIN LATERAL unnest(ix.indkey) WITH ORDINALITY AS k(attnum, ord) ON true
+JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum
+WHERE n.nspname = COALESCE(NULLIF($1, ''), current_schema())
+ORDER BY t.relname, i.relname, k.ord`
+
+	postgresForeignKeysQuery = `
+SELECT t.relname, c.conname, rt.relname, a.attname, ra.attname, c.confupdtype, c.confdeltype
+FROM pg_constraint c
+JOIN pg_class t ON t.oid = c.conrelid
+JOIN pg_class rt ON rt.oid = c.confrelid
+JOIN pg_namespace n ON n.oid = t.relnamespace
+JOIN LATERAL unnest(c.conkey, c.confkey) WITH ORDINALITY AS k(attnum, refattnum, ord) ON true
+JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum
+JOIN pg_attribute ra ON ra.attrelid = rt.oid AND ra.attnum = k.refattnum
+WHERE c.contype = 'f' AND n.nspname = COALESCE(NULLIF($1, ''), current_schema())
+ORDER BY t.relname, c.conname, k.ord`
+)
+
+var postgresActions = map[string]string{
+	"a": "",
+	"r": "RESTRICT",
+	"c": "CASCADE",
+	"n": "SET NULL",
+	"d": "SET DEFAULT",
+}
+
+func inspectPostgres(db *sql.DB, schemaName string) (*Schema, error) {
+	s := &Schema{}
+	tables := make(map[string]*Table)
+
+	rows, err := db.Query(postgresColumnsQuery, schemaName)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var (
+			tableName string
+			notNull   bool
+			col       Column
+		)
+		if err := rows.Scan(&tableName, &col.Name, &col.Type, &notNull, &col.Default); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		col.Nullable = !notNull
+		col.AutoIncrement = strings.HasPrefix(col.Default, "nextval(")
+		t, ok := tables[tableName]
+		if !ok {
+			t = &Table{Name: tableName}
+			tables[tableName] = t
+			s.Tables = append(s.Tables, t)
+		}
+		t.Columns = append(t.Columns, col)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = db.Query(postgresIndexesQuery, schemaName)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var (
+			tableName, indexName, colName string
+			unique, primary, constraint   bool
+		)
+		if err := rows.Scan(&tableName, &indexName, &unique, &primary, &constraint, &colName); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		t := tables[tableName]
+		if t == nil {
+			continue
+		}
+		if primary {
+			t.PrimaryKey = append(t.PrimaryKey, colName)
+			continue
+		}
+		if n := len(t.Indexes); n != 0 && t.Indexes[n-1].Name == indexName {
+			t.Indexes[n-1].Columns = append(t.Indexes[n-1].Columns, colName)
+			continue
+		}
+		t.Indexes = append(t.Indexes, Index{
+			Name:       indexName,
+			Columns:    []string{colName},
+			Unique:     unique,
+			Constraint: constraint,
+		})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = db.Query(postgresForeignKeysQuery, schemaName)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var tableName, name, refTable, colName, refColName, onUpdate, onDelete string
+		if err := rows.Scan(&tableName, &name, &refTable, &colName, &refColName, &onUpdate, &onDelete); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		t := tables[tableName]
+		if t == nil {
+			continue
+		}
+		if n := len(t.ForeignKeys); n != 0 && t.ForeignKeys[n-1].Name == name {
+			fk := &t.ForeignKeys[n-1]
+			fk.Columns = append(fk.Columns, colName)
+			fk.RefColumns = append(fk.RefColumns, refColName)
+			continue
+		}
+		t.ForeignKeys = append(t.ForeignKeys, ForeignKey{
+			Name:       name,
+			Columns:    []string{colName},
+			RefTable:   refTable,
+			RefColumns: []string{refColName},
+			OnUpdate:   postgresActions[onUpdate],
+			OnDelete:   postgresActions[onDelete],
+		})
+	}
+	rows.Close()
+	return s, rows.Err()
+}
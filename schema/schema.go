@@ -0,0 +1,216 @@
+// Package schema describes database schemas, either introspected from a live
+// database or built from qb tables, and computes the changes between two
+// schemas.
+package schema
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/slicebit/qb"
+)
+
+// Schema is a set of tables
+type Schema struct {
+	// Name is the database schema of the tables (an attached database on
+	// sqlite), which qualifies the table names in DDL. It is empty for the
+	// default schema.
+	Name   string
+	Tables []*Table
+}
+
+// Table returns a table by name, or nil
+func (s *Schema) Table(name string) *Table {
+	for _, t := range s.Tables {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// Table describes a table
+type Table struct {
+	Name        string
+	Columns     []Column
+	PrimaryKey  []string
+	Indexes     []Index
+	ForeignKeys []ForeignKey
+}
+
+// Column returns a column by name, or nil
+func (t *Table) Column(name string) *Column {
+	for i := range t.Columns {
+		if t.Columns[i].Name == name {
+			return &t.Columns[i]
+		}
+	}
+	return nil
+}
+
+// Column describes a column
+type Column struct {
+	Name string
+	// Type is the SQL type, as declared or reported by the database
+	Type          string
+	Nullable      bool
+	AutoIncrement bool
+	Default       string
+}
+
+// Index describes an index or a unique constraint
+type Index struct {
+	Name    string
+	Columns []string
+	Unique  bool
+	// Constraint is true if the index is created by a table constraint,
+	// and cannot be dropped as an index
+	Constraint bool
+}
+
+// ForeignKey describes a foreign key constraint
+type ForeignKey struct {
+	Name       string
+	Columns    []string
+	RefTable   string
+	RefColumns []string
+	OnUpdate   string
+	OnDelete   string
+}
+
+func hasConstraint(col qb.ColumnElem, name string) bool {
+	for _, c := range col.Constraints {
+		if strings.ToUpper(c.Name) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// FromTables builds a Schema from qb tables. prefix is prepended to the
+// table and index names, like yago.Metadata.SetTablePrefix does.
+func FromTables(dialect qb.Dialect, prefix string, tables ...qb.TableElem) *Schema {
+	s := &Schema{}
+	for _, table := range tables {
+		t := &Table{
+			Name:       prefix + table.Name,
+			PrimaryKey: table.PrimaryKeyConstraint.Columns,
+		}
+		for _, col := range table.ColumnList() {
+			t.Columns = append(t.Columns, Column{
+				Name:          col.Name,
+				Type:          dialect.CompileType(col.Type),
+				Nullable:      !col.Options.PrimaryKey && !hasConstraint(col, "NOT NULL"),
+				AutoIncrement: col.Options.AutoIncrement,
+			})
+			if col.Options.PrimaryKey && len(table.PrimaryKeyConstraint.Columns) == 0 {
+				t.PrimaryKey = append(t.PrimaryKey, col.Name)
+			}
+			if col.Options.Unique {
+				t.Indexes = append(t.Indexes, Index{
+					Columns: []string{col.Name}, Unique: true, Constraint: true,
+				})
+			}
+		}
+		if cols := table.UniqueKeyConstraint.Cols; len(cols) != 0 {
+			t.Indexes = append(t.Indexes, Index{
+				Name:       prefix + table.UniqueKeyConstraint.Name,
+				Columns:    cols,
+				Unique:     true,
+				Constraint: true,
+			})
+		}
+		for _, index := range table.Indices {
+			t.Indexes = append(t.Indexes, Index{
+				Name:    prefix + index.Name,
+				Columns: index.Columns,
+				Unique:  index.Unique,
+			})
+		}
+		for _, fk := range table.ForeignKeyConstraints.FKeys {
+			t.ForeignKeys = append(t.ForeignKeys, ForeignKey{
+				Columns:    fk.Cols,
+				RefTable:   prefix + fk.RefTable,
+				RefColumns: fk.RefCols,
+				OnUpdate:   normalizeAction(fk.Actions["UPDATE"]),
+				OnDelete:   normalizeAction(fk.Actions["DELETE"]),
+			})
+		}
+		s.Tables = append(s.Tables, t)
+	}
+	return s
+}
+
+var (
+	typeWithArgs   = regexp.MustCompile(`^([A-Z0-9_ ]+?)\s*(\(.*\))?$`)
+	typeSpaces     = regexp.MustCompile(`\s+`)
+	canonicalTypes = map[string]string{
+		"INT2":                        "SMALLINT",
+		"INT4":                        "INT",
+		"INTEGER":                     "INT",
+		"SERIAL":                      "INT",
+		"INT8":                        "BIGINT",
+		"BIGSERIAL":                   "BIGINT",
+		"CHARACTER VARYING":           "VARCHAR",
+		"CHARACTER":                   "CHAR",
+		"BPCHAR":                      "CHAR",
+		"BOOL":                        "BOOLEAN",
+		"TIMESTAMP WITHOUT TIME ZONE": "TIMESTAMP",
+		"DATETIME":                    "TIMESTAMP",
+		"TIMESTAMP WITH TIME ZONE":    "TIMESTAMPTZ",
+		"DOUBLE PRECISION":            "FLOAT",
+		"DOUBLE":                      "FLOAT",
+		"FLOAT4":                      "FLOAT",
+		"FLOAT8":                      "FLOAT",
+		"REAL":                        "FLOAT",
+		"NUMERIC":                     "DECIMAL",
+		"BYTEA":                       "BLOB",
+	}
+)
+
+// NormalizeType returns a canonical form of a SQL type, so the type names
+// of the different databases can be compared. On sqlite, all the integer
+// types are the same.
+func NormalizeType(driver string, t string) string {
+	t = typeSpaces.ReplaceAllString(strings.ToUpper(strings.TrimSpace(t)), " ")
+	sm := typeWithArgs.FindStringSubmatch(t)
+	if sm == nil {
+		return t
+	}
+	name, args := sm[1], strings.Replace(sm[2], " ", "", -1)
+	if canonical, ok := canonicalTypes[name]; ok {
+		name = canonical
+	}
+	if driver == "sqlite3" && strings.Contains(name, "INT") {
+		return "INTEGER"
+	}
+	return name + args
+}
+
+// SameType returns true if two SQL types are equivalent. A type without
+// size matches the same type with any size.
+func SameType(driver string, a string, b string) bool {
+	a, b = NormalizeType(driver, a), NormalizeType(driver, b)
+	if a == b {
+		return true
+	}
+	baseA, baseB := strings.SplitN(a, "(", 2)[0], strings.SplitN(b, "(", 2)[0]
+	return baseA == baseB && (baseA == a || baseB == b)
+}
+
+func sameColumns(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func sortTables(tables []*Table) {
+	sort.Slice(tables, func(i, j int) bool { return tables[i].Name < tables[j].Name })
+}
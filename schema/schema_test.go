@@ -0,0 +1,295 @@
+package schema_test
+
+import (
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/slicebit/qb"
+	_ "github.com/slicebit/qb/dialects/mysql"
+	_ "github.com/slicebit/qb/dialects/postgres"
+	_ "github.com/slicebit/qb/dialects/sqlite"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/orus-io/yago/schema"
+)
+
+func TestNormalizeType(t *testing.T) {
+	for _, tt := range []struct {
+		driver string
+		a, b   string
+		same   bool
+	}{
+		{"postgres", "character varying(40)", "VARCHAR(40)", true},
+		{"postgres", "character varying", "VARCHAR(255)", true},
+		{"postgres", "character varying(40)", "VARCHAR(255)", false},
+		{"postgres", "timestamp without time zone", "TIMESTAMP", true},
+		{"postgres", "timestamp with time zone", "TIMESTAMP", false},
+		{"postgres", "int4", "INTEGER", true},
+		{"postgres", "bigint", "INTEGER", false},
+		{"postgres", "numeric(10,2)", "DECIMAL(10, 2)", true},
+		{"sqlite3", "BIGINT", "INTEGER", true},
+		{"sqlite3", "bool", "BOOLEAN", true},
+		{"sqlite3", "TEXT", "VARCHAR", false},
+	} {
+		assert.Equal(t, tt.same, schema.SameType(tt.driver, tt.a, tt.b), "%s / %s", tt.a, tt.b)
+	}
+}
+
+func TestInspectSqlite(t *testing.T) {
+	engine, err := qb.New("sqlite3", ":memory:")
+	assert.Nil(t, err)
+	defer engine.Close()
+	engine.DB().SetMaxOpenConns(1)
+
+	for _, stmt := range []string{
+		`CREATE TABLE person (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name VARCHAR(40) NOT NULL,
+			email VARCHAR(255),
+			CONSTRAINT u_person_name UNIQUE (name)
+		)`,
+		`CREATE TABLE phone (
+			person_id INTEGER NOT NULL REFERENCES person ON DELETE CASCADE,
+			number VARCHAR(20) NOT NULL,
+			PRIMARY KEY (person_id, number)
+		)`,
+		`CREATE INDEX i_phone_number ON phone (number)`,
+	} {
+		_, err := engine.DB().Exec(stmt)
+		assert.Nil(t, err)
+	}
+
+	s, err := schema.Inspect(engine.DB(), "sqlite3", "")
+	assert.Nil(t, err)
+	assert.Equal(t, &schema.Schema{Tables: []*schema.Table{
+		{
+			Name: "person",
+			Columns: []schema.Column{
+				{Name: "id", Type: "INTEGER", AutoIncrement: true},
+				{Name: "name", Type: "VARCHAR(40)"},
+				{Name: "email", Type: "VARCHAR(255)", Nullable: true},
+			},
+			PrimaryKey: []string{"id"},
+			Indexes: []schema.Index{
+				{Name: "sqlite_autoindex_person_1", Columns: []string{"name"}, Unique: true, Constraint: true},
+			},
+		},
+		{
+			Name: "phone",
+			Columns: []schema.Column{
+				{Name: "person_id", Type: "INTEGER"},
+				{Name: "number", Type: "VARCHAR(20)"},
+			},
+			PrimaryKey: []string{"person_id", "number"},
+			Indexes: []schema.Index{
+				{Name: "i_phone_number", Columns: []string{"number"}},
+			},
+			ForeignKeys: []schema.ForeignKey{
+				{Columns: []string{"person_id"}, RefTable: "person", RefColumns: []string{"id"}, OnDelete: "CASCADE"},
+			},
+		},
+	}}, s)
+
+	_, err = schema.Inspect(engine.DB(), "mysql", "")
+	assert.NotNil(t, err)
+}
+
+func TestForeignKeyActions(t *testing.T) {
+	engine, err := qb.New("sqlite3", ":memory:")
+	assert.Nil(t, err)
+	defer engine.Close()
+	engine.DB().SetMaxOpenConns(1)
+
+	target := schema.FromTables(engine.Dialect(), "",
+		qb.Table("person",
+			qb.Column("id", qb.BigInt()).PrimaryKey(),
+		),
+		qb.Table("phone",
+			qb.Column("id", qb.BigInt()).PrimaryKey(),
+			qb.Column("person_id", qb.BigInt()),
+			qb.ForeignKey("person_id").References("person", "id").OnUpdate("CASCADE").OnDelete("SET NULL"),
+		),
+	)
+	for _, stmt := range target.DDL(engine.Dialect()) {
+		_, err := engine.DB().Exec(stmt)
+		assert.Nil(t, err, stmt)
+	}
+
+	current, err := schema.Inspect(engine.DB(), "sqlite3", "")
+	assert.Nil(t, err)
+	if fks := current.Table("phone").ForeignKeys; assert.Len(t, fks, 1) {
+		assert.Equal(t, "CASCADE", fks[0].OnUpdate)
+		assert.Equal(t, "SET NULL", fks[0].OnDelete)
+	}
+	assert.Len(t, schema.Diff(current, target, engine.Dialect()), 0)
+
+	// a changed action is a foreign key change
+	target.Table("phone").ForeignKeys[0].OnDelete = "CASCADE"
+	var kinds []schema.ChangeKind
+	for _, c := range schema.Diff(current, target, engine.Dialect()) {
+		kinds = append(kinds, c.Kind)
+	}
+	assert.Equal(t, []schema.ChangeKind{schema.DropForeignKey, schema.AddForeignKey}, kinds)
+}
+
+func TestDiff(t *testing.T) {
+	current := &schema.Schema{Tables: []*schema.Table{
+		{
+			Name: "person",
+			Columns: []schema.Column{
+				{Name: "id", Type: "bigint", AutoIncrement: true},
+				{Name: "name", Type: "character varying(40)", Nullable: true},
+				{Name: "age", Type: "integer"},
+			},
+			PrimaryKey: []string{"id"},
+			Indexes: []schema.Index{
+				{Name: "i_person_age", Columns: []string{"age"}},
+			},
+		},
+		{
+			Name:       "legacy",
+			Columns:    []schema.Column{{Name: "id", Type: "integer"}},
+			PrimaryKey: []string{"id"},
+		},
+	}}
+	target := &schema.Schema{Tables: []*schema.Table{
+		{
+			Name: "phone",
+			Columns: []schema.Column{
+				{Name: "person_id", Type: "BIGINT"},
+				{Name: "number", Type: "VARCHAR(20)"},
+			},
+			PrimaryKey: []string{"person_id", "number"},
+			ForeignKeys: []schema.ForeignKey{
+				{Columns: []string{"person_id"}, RefTable: "person", RefColumns: []string{"id"}},
+			},
+		},
+		{
+			Name: "person",
+			Columns: []schema.Column{
+				{Name: "id", Type: "BIGINT", AutoIncrement: true},
+				{Name: "name", Type: "TEXT"},
+				{Name: "email", Type: "VARCHAR(255)", Nullable: true},
+			},
+			PrimaryKey: []string{"id"},
+			Indexes: []schema.Index{
+				{Columns: []string{"name"}, Unique: true, Constraint: true},
+			},
+		},
+	}}
+
+	changes := schema.Diff(current, target, qb.NewDialect("postgres"))
+	var summary []string
+	for _, c := range changes {
+		summary = append(summary, c.String())
+	}
+	assert.Equal(t, []string{
+		"drop index person.i_person_age",
+		"create table phone",
+		"add column person.email",
+		"alter column person.name: type character varying(40) -> TEXT",
+		"alter column person.name: set NOT NULL",
+		"create index person.u_person_name",
+		"drop column person.age",
+		"drop table legacy",
+	}, summary)
+
+	assert.Equal(t, []string{"ALTER TABLE person ADD COLUMN email VARCHAR(255)"}, changes[2].Up)
+	assert.Equal(t, []string{"ALTER TABLE person ALTER COLUMN name TYPE TEXT USING name::TEXT"}, changes[3].Up)
+	assert.True(t, changes[3].Destructive)
+	assert.Equal(t, []string{"CREATE UNIQUE INDEX u_person_name ON person (name)"}, changes[5].Up)
+	assert.True(t, changes[6].Destructive)
+	assert.Equal(t, []string{"DROP TABLE legacy"}, changes[7].Up)
+	assert.Equal(t, []string{
+		"CREATE TABLE legacy (\n\tid integer NOT NULL,\n\tPRIMARY KEY (id)\n)",
+	}, changes[7].Down)
+
+	changes = schema.Diff(current, target, qb.NewDialect("sqlite3"))
+	assert.Equal(t, "alter column person.name: type character varying(40) -> TEXT", changes[3].String())
+	assert.Nil(t, changes[3].Up)
+	assert.NotEqual(t, "", changes[3].Review)
+
+	assert.Len(t, schema.Diff(target, target, qb.NewDialect("postgres")), 0)
+}
+
+func TestDDL(t *testing.T) {
+	s := &schema.Schema{Tables: []*schema.Table{
+		{
+			Name: "phone",
+			Columns: []schema.Column{
+				{Name: "person_id", Type: "BIGINT"},
+				{Name: "number", Type: "VARCHAR(20)"},
+			},
+			PrimaryKey: []string{"person_id", "number"},
+			ForeignKeys: []schema.ForeignKey{
+				{Columns: []string{"person_id"}, RefTable: "person", RefColumns: []string{"id"}},
+			},
+		},
+		{
+			Name: "person",
+			Columns: []schema.Column{
+				{Name: "id", Type: "BIGINT", AutoIncrement: true},
+				{Name: "name", Type: "VARCHAR(40)", Nullable: true},
+			},
+			PrimaryKey: []string{"id"},
+			Indexes: []schema.Index{
+				{Name: "i_person_name", Columns: []string{"name"}},
+			},
+		},
+	}}
+
+	assert.Equal(t, []string{
+		"CREATE TABLE person (\n\tid BIGSERIAL NOT NULL,\n\tname VARCHAR(40),\n\tPRIMARY KEY (id)\n)",
+		"CREATE INDEX i_person_name ON person (name)",
+		"CREATE TABLE phone (\n\tperson_id BIGINT NOT NULL,\n\tnumber VARCHAR(20) NOT NULL,\n\t" +
+			"PRIMARY KEY (person_id, number),\n\tFOREIGN KEY (person_id) REFERENCES person (id)\n)",
+	}, s.DDL(qb.NewDialect("postgres")))
+
+	ddl := s.DDL(qb.NewDialect("mysql"))
+	assert.Equal(t,
+		"CREATE TABLE person (\n\tid BIGINT NOT NULL AUTO_INCREMENT,\n\tname VARCHAR(40),\n\tPRIMARY KEY (id)\n)",
+		ddl[0])
+}
+
+func TestDDLCycles(t *testing.T) {
+	s := &schema.Schema{Name: "acme", Tables: []*schema.Table{
+		{
+			Name:    "node_a",
+			Columns: []schema.Column{{Name: "id", Type: "BIGINT"}, {Name: "b_id", Type: "BIGINT", Nullable: true}},
+			Indexes: []schema.Index{{Name: "i_node_a_b_id", Columns: []string{"b_id"}}},
+			ForeignKeys: []schema.ForeignKey{
+				{Columns: []string{"b_id"}, RefTable: "node_b", RefColumns: []string{"id"}},
+			},
+		},
+		{
+			Name:    "node_b",
+			Columns: []schema.Column{{Name: "id", Type: "BIGINT"}, {Name: "a_id", Type: "BIGINT"}},
+			ForeignKeys: []schema.ForeignKey{
+				{Columns: []string{"a_id"}, RefTable: "node_a", RefColumns: []string{"id"}},
+			},
+		},
+	}}
+
+	sorted, cycles := schema.SortByDependencies(s.Tables)
+	assert.Equal(t, "node_b", sorted[0].Name)
+	if assert.Len(t, cycles, 1) {
+		assert.Equal(t, "node_b", cycles[0].Table)
+		assert.Equal(t, "node_a", cycles[0].RefTable)
+	}
+
+	assert.Equal(t, []string{
+		"CREATE TABLE acme.node_b (\n\tid BIGINT NOT NULL,\n\ta_id BIGINT NOT NULL\n)",
+		"CREATE TABLE acme.node_a (\n\tid BIGINT NOT NULL,\n\tb_id BIGINT,\n\t" +
+			"FOREIGN KEY (b_id) REFERENCES acme.node_b (id)\n)",
+		"CREATE INDEX i_node_a_b_id ON acme.node_a (b_id)",
+		"ALTER TABLE acme.node_b ADD CONSTRAINT fk_node_b_a_id FOREIGN KEY (a_id) REFERENCES acme.node_a (id)",
+	}, s.DDL(qb.NewDialect("postgres")))
+
+	// sqlite creates the tables with all their foreign keys
+	ddl := s.DDL(qb.NewDialect("sqlite3"))
+	if assert.Len(t, ddl, 3) {
+		assert.Equal(t, "CREATE TABLE acme.node_b (\n\tid BIGINT NOT NULL,\n\ta_id BIGINT NOT NULL,\n\t"+
+			"FOREIGN KEY (a_id) REFERENCES node_a (id)\n)", ddl[0])
+		assert.Equal(t, "CREATE INDEX acme.i_node_a_b_id ON node_a (b_id)", ddl[2])
+	}
+}
@@ -0,0 +1,123 @@
+package yago
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/slicebit/qb"
+)
+
+// selectQuery is the statement run by a Query. The ORDER BY, LIMIT and
+// FOR UPDATE clauses are compiled by yago and appended to the qb select
+// (or compound) statement, qb having no support for per-term directions
+// or for ordering compound statements.
+type selectQuery struct {
+	body     qb.Clause
+	compound bool
+
+	orderBy []OrderTerm
+	offset  int
+	limit   *int
+	lock    *lockClause
+}
+
+// Accept compiles the statement
+func (s selectQuery) Accept(context *qb.CompilerContext) string {
+	body := s.body.Accept(context)
+	if s.compound && s.emulatesNulls(context.Dialect) {
+		// sqlite and mysql accept only result columns in the ORDER BY of a
+		// compound statement, the 'IS NULL' terms order a derived table
+		body = "SELECT *\nFROM (" + body + ") AS " + context.Dialect.Escape("compound")
+	}
+	lines := []string{body}
+	if len(s.orderBy) != 0 {
+		var terms []string
+		for _, term := range s.orderBy {
+			terms = append(terms, term.compile(context, s.compound))
+		}
+		lines = append(lines, "ORDER BY "+strings.Join(terms, ", "))
+	}
+	if s.limit != nil {
+		lines = append(lines, fmt.Sprintf("LIMIT %d OFFSET %d", *s.limit, s.offset))
+	}
+	if s.lock != nil {
+		if lock := s.lock.Accept(context); lock != "" {
+			lines = append(lines, lock)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// emulatesNulls returns true if a NULLS FIRST/LAST ordering is emulated on
+// the dialect
+func (s selectQuery) emulatesNulls(dialect qb.Dialect) bool {
+	if dialect.Driver() == "postgres" {
+		return false
+	}
+	for _, term := range s.orderBy {
+		if term.nulls != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Build compiles the statement for a dialect
+func (s selectQuery) Build(dialect qb.Dialect) *qb.Stmt {
+	return buildStatement(s, dialect)
+}
+
+// buildStatement compiles a clause for a dialect
+func buildStatement(clause qb.Clause, dialect qb.Dialect) *qb.Stmt {
+	context := qb.NewCompilerContext(dialect)
+	statement := qb.Statement()
+	statement.AddSQLClause(clause.Accept(context))
+	statement.AddBinding(context.Binds...)
+	return statement
+}
+
+// lockClause is a FOR UPDATE or FOR SHARE clause
+type lockClause struct {
+	share  bool
+	tables []qb.TableElem
+	wait   string
+}
+
+// isSqlite returns true if the dialect is sqlite, which has no row locks
+func isSqlite(dialect qb.Dialect) bool {
+	driver := dialect.Driver()
+	return driver == "sqlite3" || driver == "sqlite"
+}
+
+// check returns an error if the clause cannot be compiled for the dialect.
+// Sqlite locks the whole database when writing, so FOR UPDATE and FOR SHARE
+// can be omitted, but NOWAIT and SKIP LOCKED cannot be honored.
+func (c *lockClause) check(dialect qb.Dialect) error {
+	if c != nil && c.wait != "" && isSqlite(dialect) {
+		return fmt.Errorf("yago Query: %s is not supported by %s", c.wait, dialect.Driver())
+	}
+	return nil
+}
+
+// Accept compiles the clause. Sqlite has no row locks, the clause is
+// omitted.
+func (c lockClause) Accept(context *qb.CompilerContext) string {
+	if isSqlite(context.Dialect) {
+		return ""
+	}
+	sql := "FOR UPDATE"
+	if c.share {
+		sql = "FOR SHARE"
+	}
+	if len(c.tables) != 0 && context.Dialect.Driver() == "postgres" {
+		var tables []string
+		for _, table := range c.tables {
+			tables = append(tables, escapeUnqualified(context.Dialect, table.Name))
+		}
+		sql += " OF " + strings.Join(tables, ", ")
+	}
+	if c.wait != "" {
+		sql += " " + c.wait
+	}
+	return sql
+}
@@ -0,0 +1,170 @@
+package yago
+
+import (
+	"github.com/slicebit/qb"
+)
+
+// clauseWithErr is implemented by clauses that wrap a Query, so the
+// query building errors are not lost when the clause is used
+type clauseWithErr interface {
+	Err() error
+}
+
+// clauseWithTables is implemented by clauses that wrap a Query, so the
+// tables read by the query are known to the result cache
+type clauseWithTables interface {
+	readTables() []string
+}
+
+// compileSelect compiles a select statement in its own context, sharing
+// the binds of the enclosing statement
+func compileSelect(context *qb.CompilerContext, stmt qb.Clause) string {
+	subContext := qb.NewCompilerContext(context.Dialect)
+	subContext.Binds = context.Binds
+	sql := stmt.Accept(subContext)
+	context.Binds = subContext.Binds
+	return sql
+}
+
+// compileSubQuery compiles a select statement between parenthesis
+func compileSubQuery(context *qb.CompilerContext, stmt qb.Clause) string {
+	return "(" + compileSelect(context, stmt) + ")"
+}
+
+// inQueryClause is a 'column IN (SELECT ...)' clause
+type inQueryClause struct {
+	left  qb.Clause
+	query Query
+	not   bool
+}
+
+// Accept compiles the clause
+func (c inQueryClause) Accept(context *qb.CompilerContext) string {
+	op := " IN "
+	if c.not {
+		op = " NOT IN "
+	}
+	return c.left.Accept(context) + op + compileSubQuery(context, c.query.statement())
+}
+
+// Err returns the subquery error
+func (c inQueryClause) Err() error {
+	return c.query.err
+}
+
+func (c inQueryClause) readTables() []string {
+	return c.query.readTables()
+}
+
+// existsClause is a 'EXISTS (SELECT ...)' clause
+type existsClause struct {
+	query Query
+	not   bool
+}
+
+// Accept compiles the clause
+func (c existsClause) Accept(context *qb.CompilerContext) string {
+	sql := "EXISTS " + compileSubQuery(context, c.query.statement())
+	if c.not {
+		sql = "NOT " + sql
+	}
+	return sql
+}
+
+// Err returns the subquery error
+func (c existsClause) Err() error {
+	return c.query.err
+}
+
+func (c existsClause) readTables() []string {
+	return c.query.readTables()
+}
+
+// Exists returns a EXISTS clause on a subquery. The subquery can refer to
+// the tables of the enclosing query.
+func Exists(q Query) qb.Clause {
+	return existsClause{query: q}
+}
+
+// NotExists returns a NOT EXISTS clause on a subquery
+func NotExists(q Query) qb.Clause {
+	return existsClause{query: q, not: true}
+}
+
+// DerivedTable is a Query used as a table in a JOIN clause
+type DerivedTable struct {
+	name  string
+	query Query
+}
+
+// As returns the query as a derived table, that can be joined to another
+// query
+func (q Query) As(name string) DerivedTable {
+	return DerivedTable{name: name, query: q}
+}
+
+// Accept compiles the derived table definition
+func (t DerivedTable) Accept(context *qb.CompilerContext) string {
+	return compileSubQuery(context, t.query.statement()) + " AS " + escapeUnqualified(context.Dialect, t.name)
+}
+
+// Err returns the query error
+func (t DerivedTable) Err() error {
+	return t.query.err
+}
+
+func (t DerivedTable) readTables() []string {
+	return t.query.readTables()
+}
+
+// C returns a column of the derived table
+func (t DerivedTable) C(name string) qb.ColumnElem {
+	return qb.ColumnElem{Name: name, Table: t.name}
+}
+
+// Field returns the derived table column matching a field of the query
+func (t DerivedTable) Field(field ScalarField) ScalarField {
+	column := t.C(field.Column.Name)
+	column.Type = field.Column.Type
+	return NewScalarField(column)
+}
+
+// ColumnList returns the columns selected by the query
+func (t DerivedTable) ColumnList() []qb.ColumnElem {
+	var columns []qb.ColumnElem
+	for _, clause := range t.query.selectStmt.SelectList {
+		switch c := clause.(type) {
+		case qb.ColumnElem:
+			columns = append(columns, t.C(c.Name))
+		case ScalarField:
+			columns = append(columns, t.C(c.Column.Name))
+		}
+	}
+	return columns
+}
+
+// All returns the columns selected by the query as clauses
+func (t DerivedTable) All() []qb.Clause {
+	var clauses []qb.Clause
+	for _, column := range t.ColumnList() {
+		clauses = append(clauses, column)
+	}
+	return clauses
+}
+
+// DefaultName returns the derived table name
+func (t DerivedTable) DefaultName() string {
+	return t.name
+}
+
+// InQuery returns a IN clause on a subquery, which should select a
+// single column
+func (f ScalarField) InQuery(q Query) qb.Clause {
+	return inQueryClause{left: f, query: q}
+}
+
+// NotInQuery returns a NOT IN clause on a subquery, which should select a
+// single column
+func (f ScalarField) NotInQuery(q Query) qb.Clause {
+	return inQueryClause{left: f, query: q, not: true}
+}
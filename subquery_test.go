@@ -0,0 +1,72 @@
+package yago_test
+
+import (
+	"testing"
+
+	"github.com/orus-io/yago"
+	"github.com/slicebit/qb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubQuery(t *testing.T) {
+	db, model, cleanup := initModel(t)
+	defer cleanup()
+
+	john := PersonStruct{FirstName: "John"}
+	jane := PersonStruct{FirstName: "Jane"}
+	assert.Nil(t, db.Insert(&john))
+	assert.Nil(t, db.Insert(&jane))
+	assert.Nil(t, db.Insert(&AutoIncChild{Name: "Junior", Person: john.ID}))
+
+	children := db.Query(model.AutoIncChild).Select(model.AutoIncChild.Person)
+
+	var p PersonStruct
+
+	assert.Nil(t, db.Query(model.PersonStruct).Where(
+		model.PersonStruct.ID.InQuery(children),
+	).One(&p))
+	assert.Equal(t, "John", p.FirstName)
+
+	assert.Nil(t, db.Query(model.PersonStruct).Where(
+		model.PersonStruct.ID.NotInQuery(children),
+	).One(&p))
+	assert.Equal(t, "Jane", p.FirstName)
+
+	correlated := db.Query(model.AutoIncChild).Select(qb.SQLText("1")).Where(
+		model.AutoIncChild.Person.Column.Eq(model.PersonStruct.ID.Column),
+	)
+
+	assert.Nil(t, db.Query(model.PersonStruct).Where(
+		yago.Exists(correlated),
+	).One(&p))
+	assert.Equal(t, "John", p.FirstName)
+
+	assert.Nil(t, db.Query(model.PersonStruct).Filter(
+		yago.NotExists(correlated),
+	).One(&p))
+	assert.Equal(t, "Jane", p.FirstName)
+
+	derived := children.As("children")
+	assert.Nil(t, db.Query(model.PersonStruct).JoinQuery(
+		derived,
+		derived.Field(model.AutoIncChild.Person).Eq(model.PersonStruct.ID.Column),
+	).One(&p))
+	assert.Equal(t, "John", p.FirstName)
+}
+
+func TestSubQueryError(t *testing.T) {
+	db, model, cleanup := initModel(t)
+	defer cleanup()
+
+	invalid := db.Query(model.AutoIncChild).JoinOn(model.SimpleStruct)
+	assert.NotNil(t, invalid.Err())
+
+	q := db.Query(model.PersonStruct).Where(model.PersonStruct.ID.InQuery(invalid))
+	assert.Equal(t, invalid.Err(), q.Err())
+
+	q = db.Query(model.PersonStruct).Filter(yago.Exists(invalid))
+	assert.Equal(t, invalid.Err(), q.Err())
+
+	q = db.Query(model.PersonStruct).JoinQuery(invalid.As("invalid"))
+	assert.Equal(t, invalid.Err(), q.Err())
+}
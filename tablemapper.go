@@ -0,0 +1,71 @@
+package yago
+
+import (
+	"github.com/slicebit/qb"
+)
+
+// tableMapper maps a struct to another table than the one of its base
+// mapper. The table has the same columns and constraints.
+type tableMapper struct {
+	Mapper
+	name  string
+	table *qb.TableElem
+}
+
+// NewTableMapper returns a mapper that maps the struct of base to a copy of
+// its table named tableName. It can be registered as a named mapper with
+// Metadata.AddNamedMapper.
+func NewTableMapper(base Mapper, name string, tableName string) Mapper {
+	baseTable := base.Table()
+	table := *baseTable
+	table.Name = tableName
+	table.Columns = make(map[string]qb.ColumnElem, len(baseTable.Columns))
+	for colName, col := range baseTable.Columns {
+		col.Table = tableName
+		table.Columns[colName] = col
+	}
+	table.Indices = nil
+	for _, index := range baseTable.Indices {
+		table = table.Index(index.Columns...)
+	}
+	return &tableMapper{Mapper: base, name: name, table: &table}
+}
+
+// GetMapper returns itself
+func (m *tableMapper) GetMapper() Mapper {
+	return m
+}
+
+// Name returns the base mapper name followed by the mapper name
+func (m *tableMapper) Name() string {
+	return m.Mapper.Name() + ":" + m.name
+}
+
+// Table returns the mapper table
+func (m *tableMapper) Table() *qb.TableElem {
+	return m.table
+}
+
+// FieldList returns the list of fields for a select
+func (m *tableMapper) FieldList() []qb.Clause {
+	var fields []qb.Clause
+	for _, field := range m.Mapper.FieldList() {
+		if col, ok := field.(qb.ColumnElem); ok {
+			field = m.table.C(col.Name)
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// PKeyClause returns a clause that matches the instance primary key
+func (m *tableMapper) PKeyClause(values []interface{}) qb.Clause {
+	var clauses []qb.Clause
+	for i, col := range m.table.PrimaryCols() {
+		clauses = append(clauses, col.Eq(values[i]))
+	}
+	if len(clauses) == 1 {
+		return clauses[0]
+	}
+	return qb.And(clauses...)
+}
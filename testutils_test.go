@@ -21,7 +21,7 @@ func asSQL(query yago.Query) string {
 
 func asSQLBind(query yago.Query) (string, []interface{}) {
 	dialect := qb.NewDialect("default")
-	s := query.SelectStmt()
+	s := query.Statement()
 	ctx := qb.NewCompilerContext(dialect)
 	return s.Accept(ctx), ctx.Binds
 }
@@ -51,7 +51,7 @@ func initModelWithDriver(t *testing.T, driver string) (db *yago.DB, model Fixtur
 	db = yago.New(meta, engine)
 	CleanupDB(t, db, false)
 
-	assert.Nil(t, meta.GetQbMetadata().CreateAll(engine))
+	assert.Nil(t, meta.CreateAll(engine, false))
 	cleanup = func() { CleanupFunc(t, db, true) }
 	return
 }
@@ -62,10 +62,7 @@ func CleanupFunc(t *testing.T, db *yago.DB, reportErrors bool) {
 }
 
 func CleanupDB(t *testing.T, db *yago.DB, reportErrors bool) {
-	for _, table := range db.Metadata.GetQbMetadata().Tables() {
-		_, err := db.Engine.DB().Exec("DROP TABLE " + table.Name)
-		if err != nil && reportErrors {
-			t.Errorf("Could not drop table '%s': %s", table.Name, err)
-		}
+	if err := db.Metadata.DropAll(db.Engine, true); err != nil && reportErrors {
+		t.Errorf("Could not drop the tables: %s", err)
 	}
 }
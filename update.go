@@ -0,0 +1,167 @@
+package yago
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/slicebit/qb"
+)
+
+// Assignment is a 'column = expression' assignment of an update
+type Assignment struct {
+	column qb.ColumnElem
+	value  qb.Clause
+}
+
+// AssignableField is a field that an update can assign, a ScalarField or a
+// MarshaledScalarField
+type AssignableField interface {
+	assign(value interface{}) Assignment
+}
+
+// Set returns an assignment of a field to a value or an expression. The
+// values of a MarshaledScalarField are marshaled.
+func Set(field AssignableField, value interface{}) Assignment {
+	return field.assign(value)
+}
+
+func (f ScalarField) assign(value interface{}) Assignment {
+	return Assignment{column: f.Column, value: asClause(value)}
+}
+
+func (f MarshaledScalarField) assign(value interface{}) Assignment {
+	return Assignment{column: f.Column, value: asClause(f.marshalValue(value))}
+}
+
+// updateExprStmt is a UPDATE statement whose values are expressions
+type updateExprStmt struct {
+	table       *qb.TableElem
+	assignments []Assignment
+	where       qb.Clause
+	returning   []qb.Clause
+}
+
+// Accept compiles the statement. The columns are not qualified with the
+// table name.
+func (s updateExprStmt) Accept(context *qb.CompilerContext) string {
+	context.DefaultTableName = s.table.Name
+	var sets []string
+	for _, a := range s.assignments {
+		sets = append(sets,
+			context.Dialect.Escape(a.column.Name)+" = "+a.value.Accept(context))
+	}
+	lines := []string{
+		"UPDATE " + escapeTable(context.Dialect, s.table.Name),
+		"SET " + strings.Join(sets, ", "),
+		"WHERE " + s.where.Accept(context),
+	}
+	if len(s.returning) != 0 {
+		var returning []string
+		for _, c := range s.returning {
+			returning = append(returning, c.Accept(context))
+		}
+		lines = append(lines, "RETURNING "+strings.Join(returning, ", "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Build compiles the statement for a dialect
+func (s updateExprStmt) Build(dialect qb.Dialect) *qb.Stmt {
+	return buildStatement(s, dialect)
+}
+
+// doUpdateExpr applies the assignments to the struct record, and loads the
+// resulting values in the struct. The columns that the BeforeUpdate
+// callbacks change on the struct are written too. On postgres the values
+// are returned by the update itself, other dialects reload the record in
+// the same transaction.
+func (db *DB) doUpdateExpr(idb IDB, mapper Mapper, s MappedStruct, assignments ...Assignment) error {
+	if len(assignments) == 0 {
+		return fmt.Errorf("yago UpdateExpr: No assignment")
+	}
+	before := mapper.SQLValues(s)
+	db.Callbacks.BeforeUpdate.Call(db, s)
+	update := updateExprStmt{
+		table:       mapper.Table(),
+		assignments: append(assignments[:len(assignments):len(assignments)], callbackAssignments(mapper, before, mapper.SQLValues(s), assignments)...),
+		where:       mapper.PKeyClause(mapper.PKey(s)),
+	}
+
+	if db.Engine.Dialect().Driver() == "postgres" {
+		update.returning = mapper.FieldList()
+		rows, err := idb.GetEngine().Query(update)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		if !rows.Next() {
+			return ErrRecordNotFound
+		}
+		if err := mapper.Scan(rows, s); err != nil {
+			return fmt.Errorf("yago UpdateExpr: Error scanning the returned values: %s", err)
+		}
+		if rows.Next() {
+			return ErrMultipleRecords
+		}
+	} else if d, ok := idb.(*DB); ok {
+		tx, err := d.Begin()
+		if err != nil {
+			return err
+		}
+		if err := updateAndReload(tx, mapper, update, s); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	} else if err := updateAndReload(idb, mapper, update, s); err != nil {
+		return err
+	}
+	db.Callbacks.AfterUpdate.Call(db, s)
+	return nil
+}
+
+// callbackAssignments returns assignments of the columns which values were
+// changed by the callbacks, and are not assigned yet
+func callbackAssignments(mapper Mapper, before map[string]interface{}, after map[string]interface{}, assignments []Assignment) []Assignment {
+	assigned := make(map[string]bool)
+	for _, a := range assignments {
+		assigned[a.column.Name] = true
+	}
+	var columns []string
+	for column, value := range after {
+		if !assigned[column] && !reflect.DeepEqual(before[column], value) {
+			columns = append(columns, column)
+		}
+	}
+	sort.Strings(columns)
+	var added []Assignment
+	for _, column := range columns {
+		added = append(added, Assignment{
+			column: mapper.Table().C(column),
+			value:  qb.Bind(after[column]),
+		})
+	}
+	return added
+}
+
+// updateAndReload runs an update, and reloads the updated record
+func updateAndReload(idb IDB, mapper Mapper, update updateExprStmt, s MappedStruct) error {
+	res, err := idb.GetEngine().Exec(update)
+	if err != nil {
+		return err
+	}
+	ra, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("yago UpdateExpr: RowsAffected() failed with '%s'", err)
+	}
+	if ra == 0 {
+		return ErrRecordNotFound
+	} else if ra > 1 {
+		return ErrMultipleRecords
+	}
+	return NewQuery(idb, mapper).Where(update.where).NoCache().One(s)
+}
@@ -0,0 +1,81 @@
+package yago_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/orus-io/yago"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateExpr(t *testing.T) {
+	db, model, cleanup := initModel(t)
+	defer cleanup()
+
+	s := SimpleStruct{Name: "counter", Counter: 1}
+	assert.Nil(t, db.Insert(&s))
+
+	// Another copy of the record, which counter is not up-to-date
+	stale := SimpleStruct{ID: s.ID, Name: "counter"}
+
+	assert.Nil(t, db.UpdateExpr(&s,
+		yago.Set(model.SimpleStruct.Counter, model.SimpleStruct.Counter.Add(1))))
+	assert.Equal(t, 2, s.Counter)
+
+	assert.Nil(t, db.UpdateExpr(&stale,
+		yago.Set(model.SimpleStruct.Counter, model.SimpleStruct.Counter.Mul(10).Sub(5)),
+		yago.Set(model.SimpleStruct.Name, yago.Func("UPPER", model.SimpleStruct.Name)),
+	))
+	assert.Equal(t, 15, stale.Counter)
+	assert.Equal(t, "COUNTER", stale.Name)
+
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	assert.Nil(t, tx.UpdateExpr(&s, yago.Set(model.SimpleStruct.Counter, 0)))
+	assert.Equal(t, 0, s.Counter)
+	assert.Nil(t, tx.Rollback())
+
+	assert.Nil(t, db.Query(model.SimpleStruct).Get(&s, s.ID))
+	assert.Equal(t, 15, s.Counter)
+
+	missing := SimpleStruct{ID: s.ID + 1}
+	assert.Equal(t, yago.ErrRecordNotFound, db.UpdateExpr(&missing,
+		yago.Set(model.SimpleStruct.Counter, 0)))
+}
+
+func TestUpdateExprCallbacks(t *testing.T) {
+	db, model, cleanup := initModel(t)
+	defer cleanup()
+
+	p := PersonStruct{FirstName: "John"}
+	assert.Nil(t, db.Insert(&p))
+	updatedAt := p.UpdatedAt
+
+	// the UpdatedAt set by BeforeUpdate is written
+	time.Sleep(time.Millisecond)
+	assert.Nil(t, db.UpdateExpr(&p,
+		yago.Set(model.PersonStruct.FirstName, yago.Func("UPPER", model.PersonStruct.FirstName))))
+	assert.Equal(t, "JOHN", p.FirstName)
+	assert.True(t, p.UpdatedAt.After(updatedAt))
+
+	var loaded PersonStruct
+	assert.Nil(t, db.Query(model.PersonStruct).Get(&loaded, p.ID))
+	assert.True(t, loaded.UpdatedAt.After(updatedAt))
+}
+
+func TestUpdateExprMarshaled(t *testing.T) {
+	db, model, cleanup := initModel(t)
+	defer cleanup()
+
+	p := PersonStruct{FirstName: "John", Gender: Male}
+	assert.Nil(t, db.Insert(&p))
+
+	// the value of a marshaled field is marshaled
+	assert.Nil(t, db.UpdateExpr(&p, yago.Set(model.PersonStruct.Gender, Female)))
+	assert.Equal(t, Female, p.Gender)
+
+	var count int
+	assert.Nil(t, db.Query(model.PersonStruct).
+		Where(model.PersonStruct.Gender.Eq(Female)).Count(&count))
+	assert.Equal(t, 1, count)
+}
@@ -1,5 +1,7 @@
 package yago
 
+import "sync/atomic"
+
 // StringListContains returns true if the list containts the passed value,
 // false otherwise
 func StringListContains(list []string, value string) bool {
@@ -10,3 +12,14 @@ func StringListContains(list []string, value string) bool {
 	}
 	return false
 }
+
+// Sequence is a counter that is safe for concurrent use. The generated
+// factories use it to build unique values.
+type Sequence struct {
+	n int64
+}
+
+// Next increments the sequence and returns its value, starting at 1
+func (s *Sequence) Next() int64 {
+	return atomic.AddInt64(&s.n, 1)
+}
@@ -0,0 +1,94 @@
+package yago
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/slicebit/qb"
+)
+
+// ValidationError lists the problems found by Metadata.Validate
+type ValidationError struct {
+	Problems []string
+}
+
+// Error returns the problems
+func (e *ValidationError) Error() string {
+	return "yago Metadata.Validate(): " + strings.Join(e.Problems, "; ")
+}
+
+// Validate checks the consistency of the mappers and of their tables: the
+// foreign keys must reference registered tables and existing columns, the
+// primary key and index columns must exist, and the mappers must select
+// the columns of their own table.
+// It returns a *ValidationError listing all the problems found.
+func (m *Metadata) Validate() error {
+	var problems []string
+	addProblem := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	tables := make(map[string]Mapper)
+	for _, mapper := range m.registered {
+		name := mapper.Table().Name
+		if other, ok := tables[name]; ok && other != mapper {
+			addProblem("mappers '%s' and '%s' have the same table '%s'",
+				other.Name(), mapper.Name(), name)
+		}
+		tables[name] = mapper
+	}
+
+	for _, mapper := range m.registered {
+		table := mapper.Table()
+		checkColumns := func(what string, table *qb.TableElem, columns []string) {
+			for _, col := range columns {
+				if _, ok := table.Columns[col]; !ok {
+					addProblem("table '%s': %s column '%s' does not exist", table.Name, what, col)
+				}
+			}
+		}
+
+		if len(table.PrimaryKeyConstraint.Columns) == 0 {
+			addProblem("table '%s': no primary key", table.Name)
+		}
+		checkColumns("primary key", table, table.PrimaryKeyConstraint.Columns)
+		checkColumns("unique key", table, table.UniqueKeyConstraint.Cols)
+		for _, index := range table.Indices {
+			checkColumns(fmt.Sprintf("index '%s'", index.Name), table, index.Columns)
+		}
+
+		for _, fk := range table.ForeignKeyConstraints.FKeys {
+			checkColumns("foreign key", table, fk.Cols)
+			ref, ok := tables[fk.RefTable]
+			if !ok {
+				addProblem("table '%s': foreign key references unknown table '%s'",
+					table.Name, fk.RefTable)
+				continue
+			}
+			if len(fk.Cols) != len(fk.RefCols) {
+				addProblem("table '%s': foreign key to '%s' has %d columns, but references %d",
+					table.Name, fk.RefTable, len(fk.Cols), len(fk.RefCols))
+			}
+			checkColumns(fmt.Sprintf("'%s' referenced", table.Name), ref.Table(), fk.RefCols)
+		}
+
+		for _, field := range mapper.FieldList() {
+			col, ok := field.(qb.ColumnElem)
+			if !ok {
+				continue
+			}
+			if _, exists := table.Columns[col.Name]; col.Table != table.Name || !exists {
+				addProblem("mapper '%s': selected column '%s.%s' is not a column of '%s'",
+					mapper.Name(), col.Table, col.Name, table.Name)
+			}
+		}
+		if mapper.StructType() == nil {
+			addProblem("mapper '%s': no struct type", mapper.Name())
+		}
+	}
+
+	if len(problems) != 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package yago
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/slicebit/qb"
+
+	"github.com/orus-io/yago/schema"
+)
+
+// DriftKind is the kind of a difference found by Metadata.Verify
+type DriftKind int
+
+// The differences between the database and the mapped tables
+const (
+	DriftMissingTable DriftKind = iota
+	DriftMissingColumn
+	DriftExtraColumn
+	DriftTypeMismatch
+	DriftNullabilityMismatch
+	DriftMissingIndex
+)
+
+var driftKindNames = map[DriftKind]string{
+	DriftMissingTable:        "missing table",
+	DriftMissingColumn:       "missing column",
+	DriftExtraColumn:         "extra column",
+	DriftTypeMismatch:        "type mismatch",
+	DriftNullabilityMismatch: "nullability mismatch",
+	DriftMissingIndex:        "missing index",
+}
+
+func (k DriftKind) String() string {
+	return driftKindNames[k]
+}
+
+// Drift is a difference between a mapped table and the database
+type Drift struct {
+	Kind  DriftKind
+	Table string
+	// Name is the column or index name
+	Name string
+	// Expected and Actual are the mapped and database column types or
+	// nullabilities, or the expected index definition
+	Expected string
+	Actual   string
+}
+
+func (d Drift) String() string {
+	s := d.Kind.String() + " " + d.Table
+	if d.Name != "" {
+		s += "." + d.Name
+	}
+	switch {
+	case d.Expected != "" && d.Actual != "":
+		s += fmt.Sprintf(": expected %s, got %s", d.Expected, d.Actual)
+	case d.Expected != "":
+		s += ": " + d.Expected
+	case d.Actual != "":
+		s += ": " + d.Actual
+	}
+	return s
+}
+
+// DriftReport lists the differences found by Metadata.Verify
+type DriftReport struct {
+	Drifts []Drift
+}
+
+// OK returns true if the database matches the mapped tables
+func (r *DriftReport) OK() bool {
+	return len(r.Drifts) == 0
+}
+
+// Err returns the report as an error, or nil if it is OK
+func (r *DriftReport) Err() error {
+	if r.OK() {
+		return nil
+	}
+	return r
+}
+
+// Error returns the differences
+func (r *DriftReport) Error() string {
+	var drifts []string
+	for _, d := range r.Drifts {
+		drifts = append(drifts, d.String())
+	}
+	return "yago Metadata.Verify(): " + strings.Join(drifts, "; ")
+}
+
+// Verify introspects the database and reports the differences with the
+// mapped tables: missing tables, missing or extra columns, column type or
+// nullability mismatches and missing indexes. The drifts are the
+// corresponding changes of schema.Diff. The other tables of the database
+// are ignored.
+// The returned error is an introspection failure, the differences are
+// in the report (see DriftReport.Err).
+func (m *Metadata) Verify(engine *qb.Engine) (*DriftReport, error) {
+	current, err := schema.Inspect(engine.DB(), engine.Dialect().Driver(), m.schema)
+	if err != nil {
+		return nil, err
+	}
+	target := schema.FromTables(engine.Dialect(), m.tablePrefix, m.qbMeta.Tables()...)
+
+	report := &DriftReport{}
+	nullability := func(nullable bool) string {
+		if nullable {
+			return "NULL"
+		}
+		return "NOT NULL"
+	}
+	for _, c := range schema.Diff(current, target, engine.Dialect()) {
+		d := Drift{Table: c.Table, Name: c.Name}
+		switch c.Kind {
+		case schema.CreateTable:
+			d.Kind, d.Name = DriftMissingTable, ""
+		case schema.AddColumn:
+			d.Kind = DriftMissingColumn
+			d.Expected = target.Table(c.Table).Column(c.Name).Type
+		case schema.DropColumn:
+			d.Kind = DriftExtraColumn
+			d.Actual = current.Table(c.Table).Column(c.Name).Type
+		case schema.AlterColumn:
+			expected := target.Table(c.Table).Column(c.Name)
+			actual := current.Table(c.Table).Column(c.Name)
+			if strings.HasPrefix(c.Detail, "type ") {
+				d.Kind, d.Expected, d.Actual = DriftTypeMismatch, expected.Type, actual.Type
+			} else {
+				d.Kind = DriftNullabilityMismatch
+				d.Expected, d.Actual = nullability(expected.Nullable), nullability(actual.Nullable)
+			}
+		case schema.CreateIndex:
+			d.Kind = DriftMissingIndex
+			for _, index := range target.Table(c.Table).Indexes {
+				if schema.IndexName(c.Table, index) == c.Name {
+					d.Expected = "(" + strings.Join(index.Columns, ", ") + ")"
+					if index.Unique {
+						d.Expected = "UNIQUE " + d.Expected
+					}
+				}
+			}
+		default:
+			continue
+		}
+		report.Drifts = append(report.Drifts, d)
+	}
+	return report, nil
+}
@@ -0,0 +1,59 @@
+package yago_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/orus-io/yago"
+)
+
+func TestVerify(t *testing.T) {
+	engine := initSqliteEngine(t)
+	defer engine.Close()
+
+	meta := yago.NewMetadata()
+	NewSimpleStructModel(meta)
+	NewPersonStructModel(meta)
+
+	_, err := engine.DB().Exec(
+		"CREATE TABLE simple_struct (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT, extra INTEGER)")
+	assert.Nil(t, err)
+
+	report, err := meta.Verify(engine)
+	assert.Nil(t, err)
+	assert.False(t, report.OK())
+	assert.NotNil(t, report.Err())
+
+	type drift struct {
+		kind yago.DriftKind
+		name string
+	}
+	var drifts []drift
+	byKind := make(map[yago.DriftKind]yago.Drift)
+	for _, d := range report.Drifts {
+		drifts = append(drifts, drift{d.Kind, d.Name})
+		byKind[d.Kind] = d
+	}
+	assert.ElementsMatch(t, []drift{
+		{yago.DriftTypeMismatch, "name"},
+		{yago.DriftNullabilityMismatch, "name"},
+		{yago.DriftMissingColumn, "counter"},
+		{yago.DriftExtraColumn, "extra"},
+		{yago.DriftMissingIndex, byKind[yago.DriftMissingIndex].Name},
+		{yago.DriftMissingTable, ""},
+	}, drifts)
+	assert.Equal(t, "nullability mismatch simple_struct.name: expected NOT NULL, got NULL",
+		byKind[yago.DriftNullabilityMismatch].String())
+	assert.Equal(t, "extra column simple_struct.extra: INTEGER", byKind[yago.DriftExtraColumn].String())
+	assert.Equal(t, "UNIQUE (name)", byKind[yago.DriftMissingIndex].Expected)
+	assert.Equal(t, "person_struct", byKind[yago.DriftMissingTable].Table)
+
+	other := initSqliteEngine(t)
+	defer other.Close()
+	assert.Nil(t, meta.GetQbMetadata().CreateAll(other))
+	report, err = meta.Verify(other)
+	assert.Nil(t, err)
+	assert.True(t, report.OK(), report.Error())
+	assert.Nil(t, report.Err())
+}
@@ -0,0 +1,388 @@
+// Package yagofixtures loads test data from YAML or JSON files into a
+// database, through the yago mappers.
+//
+// A fixture file is keyed by model, the struct name, the mapper name or the
+// table name. A model holds either a list of records, or records labelled
+// by a name. A record maps column (or field) names to values. A string value
+// "$label" is replaced by the primary key of the labelled record, which is
+// inserted first ("$$" escapes a leading "$"):
+//
+//	PersonStruct:
+//	  john:
+//	    first_name: John
+//	PhoneNumber:
+//	  - person_id: $john
+//	    number: "555-1234"
+package yagofixtures
+
+import (
+	"database/sql"
+	"encoding"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/orus-io/yago"
+	"github.com/orus-io/yago/internal/tags"
+)
+
+// Fixtures is a set of records to insert
+type Fixtures struct {
+	meta    *yago.Metadata
+	records []*record
+	labels  map[string]*record
+}
+
+type record struct {
+	label    string
+	mapper   yago.Mapper
+	values   yaml.MapSlice
+	instance yago.MappedStruct
+}
+
+// New returns an empty set of fixtures for the mappers of meta
+func New(meta *yago.Metadata) *Fixtures {
+	return &Fixtures{meta: meta, labels: make(map[string]*record)}
+}
+
+// LoadFiles reads fixture files, in YAML or JSON
+func (f *Fixtures) LoadFiles(paths ...string) error {
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := f.Load(data); err != nil {
+			return fmt.Errorf("%s: %s", path, err)
+		}
+	}
+	return nil
+}
+
+// Load reads fixtures in YAML or JSON
+func (f *Fixtures) Load(data []byte) error {
+	var models yaml.MapSlice
+	if err := yaml.Unmarshal(data, &models); err != nil {
+		return fmt.Errorf("yagofixtures: %s", err)
+	}
+	for _, model := range models {
+		name := fmt.Sprint(model.Key)
+		mapper := f.lookupMapper(name)
+		if mapper == nil {
+			return fmt.Errorf("yagofixtures: Unknown model '%s'", name)
+		}
+		switch records := model.Value.(type) {
+		case yaml.MapSlice:
+			for _, item := range records {
+				if err := f.add(mapper, fmt.Sprint(item.Key), item.Value); err != nil {
+					return err
+				}
+			}
+		case []interface{}:
+			for _, item := range records {
+				if err := f.add(mapper, "", item); err != nil {
+					return err
+				}
+			}
+		case nil:
+		default:
+			return fmt.Errorf("yagofixtures: %s: Expected a list or a map of records", name)
+		}
+	}
+	return nil
+}
+
+// lookupMapper returns the mapper of a mapper name, a struct name or a
+// table name
+func (f *Fixtures) lookupMapper(name string) yago.Mapper {
+	if mapper := f.meta.MapperByName(name); mapper != nil {
+		return mapper
+	}
+	for _, mapper := range f.meta.Mappers() {
+		if mapper.StructType().Name() == name {
+			return mapper
+		}
+	}
+	return f.meta.MapperByTable(name)
+}
+
+func (f *Fixtures) add(mapper yago.Mapper, label string, value interface{}) error {
+	values, ok := value.(yaml.MapSlice)
+	if !ok && value != nil {
+		return fmt.Errorf("yagofixtures: %s: Expected a map of values, got %v", mapper.Name(), value)
+	}
+	r := &record{label: label, mapper: mapper, values: values}
+	if label != "" {
+		if _, ok := f.labels[label]; ok {
+			return fmt.Errorf("yagofixtures: Duplicate label '%s'", label)
+		}
+		f.labels[label] = r
+	}
+	f.records = append(f.records, r)
+	return nil
+}
+
+// Get returns the inserted record of a label, or nil
+func (f *Fixtures) Get(label string) yago.MappedStruct {
+	if r, ok := f.labels[label]; ok {
+		return r.instance
+	}
+	return nil
+}
+
+// InsertTx inserts the records in a transaction, which is rolled back if
+// an insert fails
+func (f *Fixtures) InsertTx(db *yago.DB) error {
+	var pending []*record
+	for _, r := range f.records {
+		if r.instance == nil {
+			pending = append(pending, r)
+		}
+	}
+	tx, err := db.Begin()
+	if err == nil {
+		if err = f.Insert(tx); err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}
+	if err != nil {
+		for _, r := range pending {
+			r.instance = nil
+		}
+	}
+	return err
+}
+
+// Insert inserts the records that are not inserted yet, the referenced
+// tables first, and the referenced records before the records that
+// reference them
+func (f *Fixtures) Insert(db yago.IDB) error {
+	position := make(map[string]int)
+	for i, table := range f.meta.TableGraph().Tables {
+		position[table] = i
+	}
+	var pending []*record
+	for _, r := range f.records {
+		if r.instance == nil {
+			pending = append(pending, r)
+		}
+	}
+	sort.SliceStable(pending, func(i, j int) bool {
+		return position[pending[i].mapper.Table().Name] < position[pending[j].mapper.Table().Name]
+	})
+
+	for len(pending) != 0 {
+		var next []*record
+		for _, r := range pending {
+			instance, ready, err := f.build(r)
+			if err != nil {
+				return err
+			}
+			if !ready {
+				next = append(next, r)
+				continue
+			}
+			if err := db.InsertWith(r.mapper, instance); err != nil {
+				return fmt.Errorf("yagofixtures: Cannot insert %s: %s", r, err)
+			}
+			r.instance = instance
+		}
+		if len(next) == len(pending) {
+			var names []string
+			for _, r := range next {
+				names = append(names, r.String())
+			}
+			return fmt.Errorf("yagofixtures: Unresolved or circular references in %s",
+				strings.Join(names, ", "))
+		}
+		pending = next
+	}
+	return nil
+}
+
+func (r *record) String() string {
+	if r.label != "" {
+		return r.mapper.Name() + " '" + r.label + "'"
+	}
+	return r.mapper.Name() + " record"
+}
+
+// build returns a new instance of the record. ready is false if the record
+// references records that are not inserted yet.
+func (f *Fixtures) build(r *record) (instance yago.MappedStruct, ready bool, err error) {
+	structType := r.mapper.StructType()
+	fields := fieldIndexes(structType)
+	value := reflect.New(structType)
+	for _, item := range r.values {
+		name := fmt.Sprint(item.Key)
+		index, ok := fields[name]
+		if !ok {
+			return nil, false, fmt.Errorf("yagofixtures: %s: Unknown column '%s'", r, name)
+		}
+		v := item.Value
+		if s, ok := v.(string); ok && strings.HasPrefix(s, "$") {
+			if strings.HasPrefix(s, "$$") {
+				v = s[1:]
+			} else {
+				ref, ok := f.labels[s[1:]]
+				if !ok {
+					return nil, false, fmt.Errorf("yagofixtures: %s: Unknown label '%s'", r, s[1:])
+				}
+				if ref.instance == nil {
+					return nil, false, nil
+				}
+				pkey := ref.mapper.PKey(ref.instance)
+				if len(pkey) != 1 {
+					return nil, false, fmt.Errorf(
+						"yagofixtures: %s: Cannot reference %s, it has a composite primary key", r, ref)
+				}
+				v = pkey[0]
+			}
+		}
+		if err := setValue(value.Elem().FieldByIndex(index), v); err != nil {
+			return nil, false, fmt.Errorf("yagofixtures: %s: Column '%s': %s", r, name, err)
+		}
+	}
+	instance, ok := value.Interface().(yago.MappedStruct)
+	if !ok {
+		return nil, false, fmt.Errorf("yagofixtures: %s: *%s is not a MappedStruct", r, structType)
+	}
+	return instance, true, nil
+}
+
+// fieldIndexes maps the column names, and the field names, of a struct to
+// its fields, following the rules of the generator
+func fieldIndexes(t reflect.Type) map[string][]int {
+	fields := make(map[string][]int)
+	names := make(map[string][]int)
+	var walk func(t reflect.Type, parent []int)
+	walk = func(t reflect.Type, parent []int) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			index := append(append([]int(nil), parent...), i)
+			if f.Anonymous && f.Type.Kind() == reflect.Struct {
+				walk(f.Type, index)
+				continue
+			}
+			if f.PkgPath != "" {
+				continue
+			}
+			column := tags.ToDBName(f.Name)
+			if tag := f.Tag.Get("yago"); tag != "" {
+				if columnTags, err := tags.ParseColumnTags(tag); err == nil && columnTags.ColumnName != "" {
+					column = columnTags.ColumnName
+				}
+			}
+			if _, ok := fields[column]; !ok {
+				fields[column] = index
+			}
+			names[f.Name] = index
+		}
+	}
+	walk(t, nil)
+	for name, index := range names {
+		if _, ok := fields[name]; !ok {
+			fields[name] = index
+		}
+	}
+	return fields
+}
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	scannerType         = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	timeLayouts         = []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02"}
+)
+
+// setValue sets a field from a decoded YAML or JSON value
+func setValue(field reflect.Value, value interface{}) error {
+	if value == nil {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+	v := reflect.ValueOf(value)
+	if v.Type().AssignableTo(field.Type()) {
+		field.Set(v)
+		return nil
+	}
+	switch {
+	case field.Type() == timeType:
+		if s, ok := value.(string); ok {
+			for _, layout := range timeLayouts {
+				if t, err := time.Parse(layout, s); err == nil {
+					field.Set(reflect.ValueOf(t))
+					return nil
+				}
+			}
+		}
+		return fmt.Errorf("Invalid time %v", value)
+	case field.Kind() == reflect.Ptr:
+		elem := reflect.New(field.Type().Elem())
+		if err := setValue(elem.Elem(), value); err != nil {
+			return err
+		}
+		field.Set(elem)
+		return nil
+	case reflect.PtrTo(field.Type()).Implements(scannerType):
+		return field.Addr().Interface().(sql.Scanner).Scan(value)
+	case reflect.PtrTo(field.Type()).Implements(textUnmarshalerType):
+		if s, ok := value.(string); ok {
+			return field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+		}
+	}
+
+	// a number must not be converted to a string
+	if field.Kind() != reflect.String && v.Type().ConvertibleTo(field.Type()) {
+		if lossyConversion(v, field.Type()) {
+			return fmt.Errorf("Cannot set a %s from %v without losing its value", field.Type(), value)
+		}
+		field.Set(v.Convert(field.Type()))
+		return nil
+	}
+	return fmt.Errorf("Cannot set a %s from %v", field.Type(), value)
+}
+
+// lossyConversion returns true if a number does not fit in the type t: a
+// float with a fractional part, or a value out of range
+func lossyConversion(v reflect.Value, t reflect.Type) bool {
+	zero := reflect.Zero(t)
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return zero.OverflowInt(v.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return v.Uint() > math.MaxInt64 || zero.OverflowInt(int64(v.Uint()))
+		case reflect.Float32, reflect.Float64:
+			f := v.Float()
+			return f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 ||
+				zero.OverflowInt(int64(f))
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return v.Int() < 0 || zero.OverflowUint(uint64(v.Int()))
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return zero.OverflowUint(v.Uint())
+		case reflect.Float32, reflect.Float64:
+			f := v.Float()
+			return f != math.Trunc(f) || f < 0 || f >= math.MaxUint64 ||
+				zero.OverflowUint(uint64(f))
+		}
+	case reflect.Float32, reflect.Float64:
+		switch v.Kind() {
+		case reflect.Float32, reflect.Float64:
+			return zero.OverflowFloat(v.Float())
+		}
+	}
+	return false
+}
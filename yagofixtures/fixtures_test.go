@@ -0,0 +1,155 @@
+package yagofixtures_test
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/slicebit/qb"
+	_ "github.com/slicebit/qb/dialects/sqlite"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/orus-io/yago"
+	"github.com/orus-io/yago/yagofixtures"
+)
+
+type Person struct {
+	ID       int64         `yago:"primary_key,auto_increment"`
+	Name     string        `yago:"unique_index"`
+	ParentID sql.NullInt64 `yago:"type=qb.BigInt(),fk=Person"`
+	Born     time.Time
+}
+
+func (Person) StructType() reflect.Type {
+	return reflect.TypeOf(Person{})
+}
+
+type Phone struct {
+	ID       int64 `yago:"primary_key,auto_increment"`
+	PersonID int64 `yago:"fk=Person"`
+	Number   string
+}
+
+func (Phone) StructType() reflect.Type {
+	return reflect.TypeOf(Phone{})
+}
+
+func initDB(t *testing.T) *yago.DB {
+	engine, err := qb.New("sqlite3", ":memory:")
+	assert.Nil(t, err)
+	engine.DB().SetMaxOpenConns(1)
+
+	meta := yago.NewMetadata()
+	for _, s := range []yago.MappedStruct{&Person{}, &Phone{}} {
+		mapper, err := yago.ReflectMapper(s, yago.ReflectOptions{AutoAttrs: true, Metadata: meta})
+		assert.Nil(t, err)
+		meta.AddMapper(mapper)
+	}
+	assert.Nil(t, meta.CreateAll(engine, false))
+	return yago.New(meta, engine)
+}
+
+const fixtures = `
+Phone:
+  - person_id: $john
+    number: "555-1234"
+  - person_id: $jane
+    number: $$1
+Person:
+  jane:
+    name: Jane
+    parent_id: $john
+    born: 2001-02-03
+  john:
+    Name: John
+    born: "2000-01-01T10:00:00Z"
+`
+
+func TestFixtures(t *testing.T) {
+	db := initDB(t)
+	defer db.Close()
+
+	dir, err := ioutil.TempDir("", "yagofixtures")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "people.yml"), []byte(fixtures), 0644))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "more.json"),
+		[]byte(`{"person": [{"name": "Anonymous", "born": "1999-12-31"}]}`), 0644))
+
+	f := yagofixtures.New(db.Metadata)
+	assert.Nil(t, f.LoadFiles(filepath.Join(dir, "people.yml"), filepath.Join(dir, "more.json")))
+	assert.Nil(t, f.Get("john"))
+	assert.Nil(t, f.InsertTx(db))
+
+	john := f.Get("john").(*Person)
+	jane := f.Get("jane").(*Person)
+	assert.NotEqual(t, int64(0), john.ID)
+	assert.Equal(t, sql.NullInt64{Int64: john.ID, Valid: true}, jane.ParentID)
+	assert.Equal(t, time.Date(2001, 2, 3, 0, 0, 0, 0, time.UTC), jane.Born.UTC())
+
+	var phones []Phone
+	assert.Nil(t, db.Query(db.Metadata.GetMapper(&Phone{})).OrderBy(
+		db.Metadata.GetMapper(&Phone{}).Table().C("id")).All(&phones))
+	if assert.Len(t, phones, 2) {
+		assert.Equal(t, john.ID, phones[0].PersonID)
+		assert.Equal(t, jane.ID, phones[1].PersonID)
+		assert.Equal(t, "$1", phones[1].Number)
+	}
+	var count int
+	assert.Nil(t, db.Query(db.Metadata.GetMapper(&Person{})).Count(&count))
+	assert.Equal(t, 3, count)
+
+	// the inserted records are not inserted again
+	assert.Nil(t, f.Insert(db))
+}
+
+func TestFixturesErrors(t *testing.T) {
+	db := initDB(t)
+	defer db.Close()
+
+	for _, data := range []string{
+		"Unknown: []",
+		"Person: [{unknown: 1}]",
+		"Person: [{name: 12}]",
+		"Person: [{name: x, id: 1.5}]",
+		"Person: [{name: x, id: 1e30}]",
+		"Person: [{name: x, id: 9223372036854775808}]",
+		"Person: [{name: x, parent_id: $missing}]",
+	} {
+		f := yagofixtures.New(db.Metadata)
+		err := f.Load([]byte(data))
+		if err == nil {
+			err = f.Insert(db)
+		}
+		assert.NotNil(t, err, data)
+	}
+
+	f := yagofixtures.New(db.Metadata)
+	assert.NotNil(t, f.Load([]byte("Person: {a: {name: a}}\nPhone: {a: {number: x}}")))
+
+	f = yagofixtures.New(db.Metadata)
+	assert.Nil(t, f.Load([]byte(`
+Person:
+  first:
+    name: First
+  a:
+    name: A
+    parent_id: $b
+  b:
+    name: B
+    parent_id: $a
+`)))
+	err := f.InsertTx(db)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "circular")
+	}
+	assert.Nil(t, f.Get("first"))
+	var count int
+	assert.Nil(t, db.Query(db.Metadata.GetMapper(&Person{})).Count(&count))
+	assert.Equal(t, 0, count)
+}
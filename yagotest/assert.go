@@ -0,0 +1,71 @@
+package yagotest
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/orus-io/yago"
+)
+
+var update = flag.Bool("yagotest.update", false, "Update the golden files of AssertSQL")
+
+// AssertRowCount checks that a query returns count rows, for example:
+//
+//	yagotest.AssertRowCount(t, db.Query(model.Person), 2)
+func AssertRowCount(t testing.TB, query yago.Query, count int) bool {
+	t.Helper()
+	var actual int
+	if err := query.Count(&actual); err != nil {
+		t.Errorf("yagotest: Cannot count the rows: %s", err)
+		return false
+	}
+	if actual != count {
+		t.Errorf("yagotest: Expected %d rows, got %d", count, actual)
+		return false
+	}
+	return true
+}
+
+// AssertSQL checks that the SQL of a query, and its binds, match the
+// golden file "testdata/<name>.<driver>.sql". The golden files are written
+// when the tests run with -yagotest.update.
+func AssertSQL(t testing.TB, query yago.Query, name string) bool {
+	t.Helper()
+	sql, binds, err := query.ToSQL()
+	if err != nil {
+		t.Errorf("yagotest: Cannot compile the query: %s", err)
+		return false
+	}
+	actual := sql + "\n"
+	if len(binds) != 0 {
+		actual += fmt.Sprintf("-- binds: %v\n", binds)
+	}
+
+	path := filepath.Join("testdata", name+"."+query.Dialect().Driver()+".sql")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+			err = ioutil.WriteFile(path, []byte(actual), 0644)
+		}
+		if err != nil {
+			t.Errorf("yagotest: Cannot write the golden file: %s", err)
+			return false
+		}
+		return true
+	}
+	expected, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Errorf("yagotest: Cannot read the golden file, run the tests with -yagotest.update to write it: %s", err)
+		return false
+	}
+	if string(expected) != actual {
+		t.Errorf("yagotest: The SQL does not match %s:\nexpected: %s\nactual:   %s",
+			path, strings.TrimSpace(string(expected)), strings.TrimSpace(actual))
+		return false
+	}
+	return true
+}
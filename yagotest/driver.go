@@ -0,0 +1,216 @@
+package yagotest
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"sync"
+
+	"github.com/slicebit/qb"
+)
+
+// The test databases are opened with a "yagotest-<driver>" driver, which
+// runs all the statements of a session in one transaction of a single
+// connection of the actual driver. The transactions opened by the tested
+// code are savepoints of this transaction.
+
+var (
+	registryLock sync.Mutex
+	registered   = make(map[string]bool)
+	sessions     = make(map[string]*session)
+	nextSession  int
+)
+
+type txDriver struct{}
+
+// register registers the "yagotest-<driver>" driver, and its dialect
+func register(driverName string) string {
+	name := "yagotest-" + driverName
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	if !registered[name] {
+		sql.Register(name, txDriver{})
+		qb.RegisterDialect(name, func() qb.Dialect { return qb.NewDialect(driverName) })
+		registered[name] = true
+	}
+	return name
+}
+
+// session is a transaction on a connection of the actual driver
+type session struct {
+	lock       sync.Mutex
+	conn       driver.Conn
+	tx         driver.Tx
+	savepoints int
+}
+
+// openSession opens a connection with the actual driver, begins a
+// transaction and returns the session name, to be used as the DSN of the
+// yagotest driver
+func openSession(drv driver.Driver, dsn string) (string, error) {
+	conn, err := drv.Open(dsn)
+	if err != nil {
+		return "", err
+	}
+	tx, err := conn.Begin()
+	if err != nil {
+		conn.Close()
+		return "", err
+	}
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	nextSession++
+	name := fmt.Sprintf("yagotest_%d", nextSession)
+	sessions[name] = &session{conn: conn, tx: tx}
+	return name, nil
+}
+
+// closeSession rolls back the transaction of a session and closes its
+// connection
+func closeSession(name string) error {
+	registryLock.Lock()
+	s := sessions[name]
+	delete(sessions, name)
+	registryLock.Unlock()
+	if s == nil {
+		return nil
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	err := s.tx.Rollback()
+	if closeErr := s.conn.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func (s *session) exec(query string) error {
+	stmt, err := s.conn.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.Exec(nil)
+	return err
+}
+
+func (txDriver) Open(name string) (driver.Conn, error) {
+	registryLock.Lock()
+	s := sessions[name]
+	registryLock.Unlock()
+	if s == nil {
+		return nil, fmt.Errorf("yagotest: Unknown session '%s'", name)
+	}
+	return conn{s}, nil
+}
+
+// conn shares the connection of a session. The calls to the actual
+// connection, its statements and its rows are serialized by the session
+// lock.
+type conn struct {
+	s *session
+}
+
+func (c conn) Prepare(query string) (driver.Stmt, error) {
+	c.s.lock.Lock()
+	defer c.s.lock.Unlock()
+	st, err := c.s.conn.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	return stmt{s: c.s, stmt: st}, nil
+}
+
+// Close does nothing, the connection is closed with the session
+func (c conn) Close() error {
+	return nil
+}
+
+// Begin creates a savepoint
+func (c conn) Begin() (driver.Tx, error) {
+	c.s.lock.Lock()
+	defer c.s.lock.Unlock()
+	c.s.savepoints++
+	sp := savepoint{s: c.s, name: fmt.Sprintf("yagotest_sp_%d", c.s.savepoints)}
+	if err := c.s.exec("SAVEPOINT " + sp.name); err != nil {
+		return nil, err
+	}
+	return sp, nil
+}
+
+// savepoint is a transaction opened by the tested code
+type savepoint struct {
+	s    *session
+	name string
+}
+
+// Commit releases the savepoint
+func (sp savepoint) Commit() error {
+	sp.s.lock.Lock()
+	defer sp.s.lock.Unlock()
+	return sp.s.exec("RELEASE SAVEPOINT " + sp.name)
+}
+
+// Rollback rolls back to the savepoint, and releases it
+func (sp savepoint) Rollback() error {
+	sp.s.lock.Lock()
+	defer sp.s.lock.Unlock()
+	if err := sp.s.exec("ROLLBACK TO SAVEPOINT " + sp.name); err != nil {
+		return err
+	}
+	return sp.s.exec("RELEASE SAVEPOINT " + sp.name)
+}
+
+// stmt is a statement of the actual connection
+type stmt struct {
+	s    *session
+	stmt driver.Stmt
+}
+
+func (st stmt) Close() error {
+	st.s.lock.Lock()
+	defer st.s.lock.Unlock()
+	return st.stmt.Close()
+}
+
+func (st stmt) NumInput() int {
+	return st.stmt.NumInput()
+}
+
+func (st stmt) Exec(args []driver.Value) (driver.Result, error) {
+	st.s.lock.Lock()
+	defer st.s.lock.Unlock()
+	return st.stmt.Exec(args)
+}
+
+func (st stmt) Query(args []driver.Value) (driver.Rows, error) {
+	st.s.lock.Lock()
+	defer st.s.lock.Unlock()
+	r, err := st.stmt.Query(args)
+	if err != nil {
+		return nil, err
+	}
+	return rows{s: st.s, rows: r}, nil
+}
+
+// rows are the rows of a statement of the actual connection
+type rows struct {
+	s    *session
+	rows driver.Rows
+}
+
+func (r rows) Columns() []string {
+	return r.rows.Columns()
+}
+
+func (r rows) Close() error {
+	r.s.lock.Lock()
+	defer r.s.lock.Unlock()
+	return r.rows.Close()
+}
+
+func (r rows) Next(dest []driver.Value) error {
+	r.s.lock.Lock()
+	defer r.s.lock.Unlock()
+	return r.rows.Next(dest)
+}
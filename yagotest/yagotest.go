@@ -0,0 +1,152 @@
+// Package yagotest provides test databases for the yago models.
+//
+// A Database creates the tables of a Metadata once, and gives each test a
+// yago.DB which runs in a transaction that is rolled back when the test
+// ends. The transactions opened by the tested code are savepoints of this
+// transaction, so they can be committed or rolled back as usual:
+//
+//	var testDB *yagotest.Database
+//
+//	func TestMain(m *testing.M) {
+//		var err error
+//		testDB, err = yagotest.DatabaseFromEnv(model.NewMetadata())
+//		if err != nil {
+//			log.Fatal(err)
+//		}
+//		code := m.Run()
+//		testDB.Close()
+//		os.Exit(code)
+//	}
+//
+//	func TestSomething(t *testing.T) {
+//		db, cleanup := testDB.DB(t)
+//		defer cleanup()
+//		...
+//	}
+//
+// The database/sql driver is imported by the caller, yagotest only imports
+// the qb dialects:
+//
+//	import _ "github.com/mattn/go-sqlite3"
+//
+// A yago.DB is not safe for concurrent use, and the tests that share a
+// sqlite Database must not run in parallel.
+package yagotest
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/slicebit/qb"
+	_ "github.com/slicebit/qb/dialects/postgres"
+	_ "github.com/slicebit/qb/dialects/sqlite"
+
+	"github.com/orus-io/yago"
+)
+
+// Database is a test database that has the tables of a Metadata
+type Database struct {
+	Metadata *yago.Metadata
+
+	driver string
+	dsn    string
+	engine *qb.Engine
+}
+
+var (
+	memoryLock sync.Mutex
+	nextMemory int
+)
+
+// NewDatabase connects to a database and creates the tables of meta,
+// dropping them first if they exist. The ":memory:" sqlite3 DSN is a
+// private in-memory database.
+func NewDatabase(meta *yago.Metadata, driver, dsn string) (*Database, error) {
+	if driver == "sqlite3" && dsn == ":memory:" {
+		// the sessions need their own connections to the same database
+		memoryLock.Lock()
+		nextMemory++
+		dsn = fmt.Sprintf("file:yagotest_%d?mode=memory&cache=shared", nextMemory)
+		memoryLock.Unlock()
+	}
+	engine, err := qb.New(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := meta.DropAll(engine, true); err != nil {
+		engine.Close()
+		return nil, err
+	}
+	if err := meta.CreateAll(engine, false); err != nil {
+		engine.Close()
+		return nil, err
+	}
+	return &Database{Metadata: meta, driver: driver, dsn: dsn, engine: engine}, nil
+}
+
+// DatabaseFromEnv returns a NewDatabase on the postgres database of the
+// YAGO_TEST_POSTGRES DSN if it is set, or on an in-memory sqlite3 database
+func DatabaseFromEnv(meta *yago.Metadata) (*Database, error) {
+	if dsn := os.Getenv("YAGO_TEST_POSTGRES"); dsn != "" {
+		return NewDatabase(meta, "postgres", dsn)
+	}
+	return NewDatabase(meta, "sqlite3", ":memory:")
+}
+
+// Driver returns the name of the database driver
+func (d *Database) Driver() string {
+	return d.driver
+}
+
+// DB returns a yago.DB that runs in a transaction. The cleanup function
+// rolls back the transaction and closes the DB.
+func (d *Database) DB(t testing.TB) (db *yago.DB, cleanup func()) {
+	t.Helper()
+	name, err := openSession(d.engine.DB().Driver(), d.dsn)
+	if err != nil {
+		t.Fatalf("yagotest: Cannot open a session: %s", err)
+	}
+	engine, err := qb.New(register(d.driver), name)
+	if err != nil {
+		closeSession(name)
+		t.Fatalf("yagotest: Cannot open a session: %s", err)
+	}
+	db = yago.New(d.Metadata, engine)
+	cleanup = func() {
+		db.Close()
+		if err := closeSession(name); err != nil {
+			t.Errorf("yagotest: Cannot roll back the session: %s", err)
+		}
+	}
+	return db, cleanup
+}
+
+// Close drops the tables and closes the database
+func (d *Database) Close() error {
+	err := d.Metadata.DropAll(d.engine, true)
+	if closeErr := d.engine.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// New returns a yago.DB on a DatabaseFromEnv of its own, for the tests
+// that do not share a Database. The cleanup function drops the tables and
+// closes the database.
+func New(t testing.TB, meta *yago.Metadata) (db *yago.DB, cleanup func()) {
+	t.Helper()
+	database, err := DatabaseFromEnv(meta)
+	if err != nil {
+		t.Fatalf("yagotest: Cannot create the database: %s", err)
+	}
+	db, closeDB := database.DB(t)
+	cleanup = func() {
+		closeDB()
+		if err := database.Close(); err != nil {
+			t.Errorf("yagotest: Cannot close the database: %s", err)
+		}
+	}
+	return db, cleanup
+}
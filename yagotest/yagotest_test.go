@@ -0,0 +1,138 @@
+package yagotest_test
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/orus-io/yago"
+	"github.com/orus-io/yago/yagotest"
+)
+
+type Person struct {
+	ID   int64 `yago:"primary_key,auto_increment"`
+	Name string
+}
+
+func (Person) StructType() reflect.Type {
+	return reflect.TypeOf(Person{})
+}
+
+func newMetadata(t *testing.T) (*yago.Metadata, yago.Mapper) {
+	meta := yago.NewMetadata()
+	mapper, err := yago.ReflectMapper(&Person{}, yago.ReflectOptions{AutoAttrs: true, Metadata: meta})
+	assert.Nil(t, err)
+	meta.AddMapper(mapper)
+	return meta, mapper
+}
+
+// recorder records the errors of the assertions expected to fail
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestDatabase(t *testing.T) {
+	meta, person := newMetadata(t)
+	database, err := yagotest.DatabaseFromEnv(meta)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer database.Close()
+
+	db, cleanup := database.DB(t)
+	assert.Nil(t, db.Insert(&Person{Name: "John"}))
+
+	// the transactions of the tested code are savepoints
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	assert.Nil(t, tx.Insert(&Person{Name: "Jane"}))
+	assert.Nil(t, tx.Rollback())
+	yagotest.AssertRowCount(t, db.Query(person), 1)
+
+	tx, err = db.Begin()
+	assert.Nil(t, err)
+	assert.Nil(t, tx.Insert(&Person{Name: "Jane"}))
+	assert.Nil(t, tx.Commit())
+	yagotest.AssertRowCount(t, db.Query(person), 2)
+
+	r := &recorder{TB: t}
+	assert.False(t, yagotest.AssertRowCount(r, db.Query(person), 3))
+	assert.Equal(t, []string{"yagotest: Expected 3 rows, got 2"}, r.errors)
+	cleanup()
+
+	// the next test starts with empty tables
+	db, cleanup = database.DB(t)
+	defer cleanup()
+	yagotest.AssertRowCount(t, db.Query(person), 0)
+}
+
+func TestConcurrentStatements(t *testing.T) {
+	meta, _ := newMetadata(t)
+	db, cleanup := yagotest.New(t, meta)
+	defer cleanup()
+
+	// the statements of the session share one connection
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := db.Engine.DB().Exec("INSERT INTO person (name) VALUES ('John')")
+			assert.Nil(t, err)
+			var count int
+			assert.Nil(t, db.Engine.DB().QueryRow("SELECT COUNT(*) FROM person").Scan(&count))
+		}()
+	}
+	wg.Wait()
+
+	var count int
+	assert.Nil(t, db.Engine.DB().QueryRow("SELECT COUNT(*) FROM person").Scan(&count))
+	assert.Equal(t, 10, count)
+}
+
+func TestAssertSQL(t *testing.T) {
+	meta, person := newMetadata(t)
+	db, cleanup := yagotest.New(t, meta)
+	defer cleanup()
+
+	wd, err := os.Getwd()
+	assert.Nil(t, err)
+	dir, err := ioutil.TempDir("", "yagotest")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	assert.Nil(t, os.Chdir(dir))
+	defer os.Chdir(wd)
+
+	q := db.Query(person).Where(person.Table().C("name").Eq("John"))
+	r := &recorder{TB: t}
+	assert.False(t, yagotest.AssertSQL(r, q, "person_by_name"))
+	assert.Len(t, r.errors, 1)
+
+	assert.Nil(t, flag.Set("yagotest.update", "true"))
+	assert.True(t, yagotest.AssertSQL(t, q, "person_by_name"))
+	assert.Nil(t, flag.Set("yagotest.update", "false"))
+
+	golden, err := ioutil.ReadFile(filepath.Join(
+		"testdata", "person_by_name."+q.Dialect().Driver()+".sql"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(golden), "-- binds: [John]\n")
+
+	assert.True(t, yagotest.AssertSQL(t, q, "person_by_name"))
+	r = &recorder{TB: t}
+	assert.False(t, yagotest.AssertSQL(r, db.Query(person), "person_by_name"))
+	assert.Len(t, r.errors, 1)
+}